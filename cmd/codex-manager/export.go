@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"codex-manager/internal/config"
+	"codex-manager/internal/sessions"
+	"codex-manager/internal/web"
+)
+
+type exportItem struct {
+	Line      int    `json:"line"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Type      string `json:"type"`
+	Subtype   string `json:"subtype,omitempty"`
+	Role      string `json:"role,omitempty"`
+	Title     string `json:"title"`
+	Content   string `json:"content"`
+	Output    string `json:"output,omitempty"`
+}
+
+type exportDocument struct {
+	Date  string                `json:"date"`
+	File  string                `json:"file"`
+	Meta  *sessions.SessionMeta `json:"meta,omitempty"`
+	Items []exportItem          `json:"items"`
+}
+
+// runExport prints a single session as Markdown or JSON without starting the servers.
+func runExport(args []string, stdout io.Writer) error {
+	cfg, err := config.ParseExport(args)
+	if err != nil {
+		return err
+	}
+	sessions.SetTrimUserRequestEnabled(!cfg.NoTrimRequest)
+	sessions.SetTrimUserRequestMarkers(cfg.TrimMarkers)
+	sessions.SetTrimFromLastMarker(cfg.TrimLast)
+	sessions.SetMergeConsecutiveEnabled(!cfg.NoMerge)
+	sessions.SetFuseToolCallsEnabled(cfg.FuseToolCalls)
+	sessions.SetSortByTimeEnabled(cfg.SortByTime)
+	sessions.SetLenientParseEnabled(cfg.Lenient)
+
+	date, ok := sessions.ParseDateLabel(cfg.Date)
+	if !ok {
+		return fmt.Errorf("invalid date %q (want yyyy-mm-dd)", cfg.Date)
+	}
+
+	idx := sessions.NewIndex(cfg.SessionsDir)
+	idx.SetPathPattern(cfg.PathPattern)
+	idx.SetFollowSymlinks(cfg.FollowSymlinks)
+	if err := idx.Refresh(); err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	file, ok := idx.Lookup(date, cfg.File)
+	if !ok {
+		return fmt.Errorf("session %s/%s not found in %s", date.Path(), cfg.File, cfg.SessionsDir)
+	}
+
+	session, err := sessions.ParseSession(file.Path)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Format == "json" {
+		doc := exportDocument{
+			Date:  date.String(),
+			File:  file.Name,
+			Meta:  session.Meta,
+			Items: make([]exportItem, 0, len(session.Items)),
+		}
+		for _, item := range session.Items {
+			doc.Items = append(doc.Items, exportItem{
+				Line:      item.Line,
+				Timestamp: item.Timestamp,
+				Type:      item.Type,
+				Subtype:   item.Subtype,
+				Role:      item.Role,
+				Title:     item.Title,
+				Content:   item.Content,
+				Output:    item.Output,
+			})
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	}
+
+	_, err = io.WriteString(stdout, web.RenderSessionMarkdown(session.Items))
+	return err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codex-manager/internal/sessions"
+)
+
+func TestRunExport(t *testing.T) {
+	sessionsDir := t.TempDir()
+	dir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\",\"cwd\":\"/tmp\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hello\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi there\"}]}}\n"
+	if err := os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := runExport([]string{"-sessions-dir", sessionsDir, "2026-01-09", "s.jsonl"}, &out); err != nil {
+		t.Fatalf("runExport md: %v", err)
+	}
+	if !strings.Contains(out.String(), "## User\n\nHello") || !strings.Contains(out.String(), "## Agent\n\nHi there") {
+		t.Fatalf("unexpected markdown: %q", out.String())
+	}
+
+	out.Reset()
+	if err := runExport([]string{"-sessions-dir", sessionsDir, "-format", "json", "2026/01/09", "s.jsonl"}, &out); err != nil {
+		t.Fatalf("runExport json: %v", err)
+	}
+	var doc exportDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.Meta == nil || doc.Meta.ID != "abc" || len(doc.Items) != 2 {
+		t.Fatalf("unexpected export document: %+v", doc)
+	}
+
+	if err := runExport([]string{"-sessions-dir", sessionsDir, "2026-01-09", "missing.jsonl"}, &out); err == nil {
+		t.Fatalf("expected missing-session error")
+	}
+
+	// -lenient keeps envelopes the parser does not know, like the server does.
+	unknown := data + "{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"future_event\",\"payload\":{\"note\":\"later\"}}\n"
+	if err := os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(unknown), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	defer sessions.SetLenientParseEnabled(false)
+	for _, lenient := range []bool{false, true} {
+		args := []string{"-sessions-dir", sessionsDir, "-format", "json", "2026-01-09", "s.jsonl"}
+		if lenient {
+			args = append([]string{"-lenient"}, args...)
+		}
+		out.Reset()
+		if err := runExport(args, &out); err != nil {
+			t.Fatalf("runExport lenient=%v: %v", lenient, err)
+		}
+		doc = exportDocument{}
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		want := 2
+		if lenient {
+			want = 3
+		}
+		if len(doc.Items) != want {
+			t.Fatalf("lenient=%v: expected %d items, got %+v", lenient, want, doc.Items)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// newLogger returns a JSON-lines logger writing to w when jsonOutput is set,
+// otherwise the default logger (plain text through the standard log package).
+func newLogger(jsonOutput bool, w io.Writer) *slog.Logger {
+	if !jsonOutput {
+		return slog.Default()
+	}
+	return slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// fatal logs msg at error level with the given attributes and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// infof adapts printf-style callbacks, such as the Tailscale dry run's, to
+// the default slog logger so -log-json output stays JSON.
+func infof(format string, args ...any) {
+	slog.Info(fmt.Sprintf(format, args...))
+}
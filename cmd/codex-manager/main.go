@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
@@ -23,87 +24,191 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExport(os.Args[2:], os.Stdout); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			log.Fatalf("export error: %v", err)
+		}
+		return
+	}
+
 	cfg, err := config.Parse(os.Args[1:])
 	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			return
 		}
+		if errors.Is(err, config.ErrVersion) {
+			fmt.Println(versionString())
+			return
+		}
 		log.Fatalf("config error: %v", err)
 	}
+	slog.SetDefault(newLogger(cfg.LogJSON, os.Stderr))
 	sessions.SetTrimUserRequestEnabled(!cfg.NoTrimRequest)
+	sessions.SetTrimUserRequestMarkers(cfg.TrimMarkers)
+	sessions.SetTrimFromLastMarker(cfg.TrimLast)
+	sessions.SetMergeConsecutiveEnabled(!cfg.NoMerge)
+	sessions.SetFuseToolCallsEnabled(cfg.FuseToolCalls)
+	sessions.SetSortByTimeEnabled(cfg.SortByTime)
+	sessions.SetLenientParseEnabled(cfg.Lenient)
+	sessions.SetParseCacheBytes(cfg.ParseCache)
 
 	htmlBucketClient, htmlBucketAuthPath, err := setupHTMLBucket(cfg, os.Stdin, os.Stdout)
 	if err != nil {
-		log.Fatalf("htmlbucket setup error: %v", err)
+		fatal("htmlbucket setup failed", "error", err)
 	}
 
 	idx := sessions.NewIndex(cfg.SessionsDir)
+	idx.SetPathPattern(cfg.PathPattern)
+	idx.SetFollowSymlinks(cfg.FollowSymlinks)
+	if err := idx.SetIgnorePatterns(cfg.Ignore); err != nil {
+		fatal("invalid config", "error", err)
+	}
 	if err := idx.Refresh(); err != nil {
-		log.Printf("initial scan failed: %v", err)
+		if idx.BaseDirMissing() {
+			slog.Warn("sessions directory not found; retrying on each rescan", "path", cfg.SessionsDir)
+		} else {
+			slog.Error("initial scan failed", "error", err, "path", cfg.SessionsDir)
+		}
+	}
+	for _, key := range idx.DuplicateNames() {
+		slog.Warn("several session files share a date and name; only the newest opens by that name", "session", key)
 	}
 
 	searchIdx := search.NewIndex()
+	searchIdx.SetMaxFileSize(cfg.MaxParseSize)
 	if err := searchIdx.RefreshFrom(idx); err != nil {
-		log.Printf("initial search index build failed: %v", err)
+		slog.Error("initial search index build failed", "error", err)
+	}
+
+	renderer, err := render.New()
+	if err != nil {
+		fatal("template error", "error", err)
+	}
+
+	server := web.NewServer(idx, searchIdx, renderer, cfg.SessionsDir, cfg.ShareDir, cfg.ShareAddr, cfg.Theme)
+	server.SetMaxParseSize(cfg.MaxParseSize)
+	server.SetTruncateItems(cfg.TruncateItems)
+	server.SetThemeColors(cfg.ThemePrimary, cfg.ThemeBg, cfg.ThemeAccent)
+	server.SetLocation(cfg.Location)
+	server.SetShareRateLimit(cfg.ShareRate)
+	server.SetShareConcurrency(cfg.ShareConc)
+	server.SetShareMode(cfg.ShareMode)
+	server.SetSearchLimits(cfg.SearchDefaultLimit, cfg.SearchMaxLimit, cfg.SearchMinQuery)
+	if err := server.SetIndexDefaults(cfg.DefaultView, cfg.DefaultHeat); err != nil {
+		fatal("invalid config", "error", err)
+	}
+	server.SetKeyBindings(cfg.KeyBindings)
+	server.SetHideEmpty(cfg.HideEmpty)
+	server.SetReadOnly(cfg.ReadOnly)
+	if cfg.Metrics {
+		server.EnableMetrics()
+	}
+	if cfg.PriceTable != "" {
+		prices, err := sessions.LoadPriceTable(cfg.PriceTable)
+		if err != nil {
+			fatal("price table error", "error", err, "path", cfg.PriceTable)
+		}
+		server.SetPriceTable(prices)
+	}
+	if cfg.EditorCommand != "" {
+		if err := server.EnableEditor(cfg.EditorCommand); err != nil {
+			fatal("invalid config", "error", err)
+		}
+		slog.Info("Open-in-editor enabled", "command", cfg.EditorCommand)
+	}
+	if cfg.ArchiveDir != "" {
+		server.EnableArchive(cfg.ArchiveDir)
+		slog.Info("Archive enabled", "path", cfg.ArchiveDir)
+	}
+	if cfg.StateDir != "" {
+		if err := server.EnableStateDir(cfg.StateDir); err != nil {
+			fatal("state dir error", "error", err, "path", cfg.StateDir)
+		}
+		slog.Info("State dir enabled", "path", cfg.StateDir)
+	}
+	if cfg.SinglePort {
+		server.EnableSinglePortShares(cfg.ShareCSP)
+	}
+	if htmlBucketClient != nil {
+		server.EnableHTMLBucket(htmlBucketClient)
+		slog.Info("Using htmlbucket share backend", "path", htmlBucketAuthPath)
+	} else {
+		slog.Info("Using local share backend", "path", cfg.ShareDir)
+	}
+	var handler http.Handler = server
+	if cfg.Gzip {
+		handler = web.Gzip(handler)
+	}
+	shareServer := web.NewShareServer(cfg.ShareDir, cfg.ShareCSP)
+	if cfg.ShareGzip {
+		shareServer = web.Gzip(shareServer)
 	}
 
 	go func() {
 		ticker := time.NewTicker(cfg.RescanInterval)
 		defer ticker.Stop()
 		for range ticker.C {
-			if err := idx.Refresh(); err != nil {
-				log.Printf("rescan failed: %v", err)
-				continue
-			}
-			if err := searchIdx.RefreshFrom(idx); err != nil {
-				log.Printf("search reindex failed: %v", err)
+			if _, err := server.Rescan(); err != nil {
+				if errors.Is(err, web.ErrRefreshInProgress) {
+					continue
+				}
+				slog.Error("rescan failed", "error", err, "path", cfg.SessionsDir)
 			}
 		}
 	}()
 
-	renderer, err := render.New()
-	if err != nil {
-		log.Fatalf("template error: %v", err)
+	slog.Info("Codex sessions server listening", "addr", cfg.Addr)
+	useTLS := cfg.TLSCert != ""
+	// Share URLs take their scheme from the main request, so the share server
+	// uses the same certificate unless Tailscale is proxying to it over HTTP.
+	shareTLS := useTLS && !cfg.UseTailscale
+	browserURL := cfg.BrowserURL
+	if browserURL == "" {
+		browserURL = urlForAddr(cfg.Addr, useTLS)
 	}
-
-	server := web.NewServer(idx, searchIdx, renderer, cfg.SessionsDir, cfg.ShareDir, cfg.ShareAddr, cfg.Theme)
-	if htmlBucketClient != nil {
-		server.EnableHTMLBucket(htmlBucketClient)
-		log.Printf("Using htmlbucket share backend (%s)", htmlBucketAuthPath)
+	slog.Info("Open the UI", "url", browserURL)
+	if cfg.SinglePort {
+		slog.Info("Serving shares from the main server", "path", "/shared/")
 	} else {
-		log.Printf("Using local share backend (%s)", cfg.ShareDir)
+		slog.Info("Share server listening", "addr", cfg.ShareBind)
 	}
-	shareServer := web.NewShareServer(cfg.ShareDir)
-
-	log.Printf("Codex sessions server listening on %s", cfg.Addr)
-	log.Printf("Open the UI at %s", urlForAddr(cfg.Addr))
-	log.Printf("Share server listening on %s", cfg.ShareAddr)
-	log.Printf("Watching sessions in %s", cfg.SessionsDir)
+	slog.Info("Watching sessions", "path", cfg.SessionsDir)
 	if cfg.OpenBrowser {
 		go func() {
 			time.Sleep(250 * time.Millisecond)
-			if err := openBrowser(urlForAddr(cfg.Addr)); err != nil {
-				log.Printf("failed to open browser: %v", err)
+			if err := openBrowser(browserURL); err != nil {
+				slog.Error("failed to open browser", "error", err)
 			}
 		}()
 	}
-	go func() {
-		if err := http.ListenAndServe(cfg.ShareAddr, shareServer); err != nil {
-			log.Fatalf("share server error: %v", err)
+	if !cfg.SinglePort {
+		go func() {
+			if err := listenAndServe(cfg.ShareBind, shareServer, shareTLS, cfg.TLSCert, cfg.TLSKey); err != nil {
+				fatal("share server error", "error", err, "addr", cfg.ShareBind)
+			}
+		}()
+	}
+	if cfg.TailscaleDry {
+		host, err := web.SetupTailscaleDryRun(cfg.ShareAddr, cfg.TSTimeout, infof)
+		if err != nil {
+			fatal("tailscale dry run failed", "error", err, "hint", tailscaleHint(err))
 		}
-	}()
-	if cfg.UseTailscale {
-		host, err := web.SetupTailscale(cfg.ShareAddr)
+		slog.Info("Tailscale dry run: share URLs would use this host (tailscale not configured)", "host", host)
+	} else if cfg.UseTailscale {
+		host, err := web.SetupTailscale(cfg.ShareAddr, cfg.TSTimeout)
 		if err != nil {
-			log.Fatalf("tailscale setup error: %v", err)
+			fatal("tailscale setup failed", "error", err, "hint", tailscaleHint(err))
 		}
 		server.EnableTailscale(host)
-		log.Printf("Tailscale share host: %s", host)
+		slog.Info("Tailscale share host", "host", host)
 	} else {
-		log.Printf("Not using tailscale share")
+		slog.Info("Not using tailscale share")
 	}
-	if err := http.ListenAndServe(cfg.Addr, server); err != nil {
-		log.Fatalf("server error: %v", err)
+	if err := listenAndServe(cfg.Addr, handler, useTLS, cfg.TLSCert, cfg.TLSKey); err != nil {
+		fatal("server error", "error", err, "addr", cfg.Addr)
 	}
 }
 
@@ -142,10 +247,21 @@ func setupHTMLBucket(cfg config.Config, stdin io.Reader, stdout io.Writer) (*htm
 	}
 }
 
-func urlForAddr(addr string) string {
+func listenAndServe(addr string, handler http.Handler, useTLS bool, certFile, keyFile string) error {
+	if useTLS {
+		return http.ListenAndServeTLS(addr, certFile, keyFile, handler)
+	}
+	return http.ListenAndServe(addr, handler)
+}
+
+func urlForAddr(addr string, useTLS bool) string {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
-		return "http://" + strings.TrimRight(addr, "/") + "/"
+		return scheme + "://" + strings.TrimRight(addr, "/") + "/"
 	}
 	if host == "" || host == "0.0.0.0" || host == "::" {
 		host = "localhost"
@@ -153,14 +269,15 @@ func urlForAddr(addr string) string {
 	if strings.Contains(host, ":") {
 		host = "[" + host + "]"
 	}
-	return fmt.Sprintf("http://%s:%s/", host, port)
+	return fmt.Sprintf("%s://%s:%s/", scheme, host, port)
 }
 
 func openBrowser(url string) error {
 	var cmd *exec.Cmd
 	if isWSL() {
-		cmd = exec.Command("cmd.exe", "/c", "start", "", url)
-		return cmd.Start()
+		if cmd = wslOpenCommand(url); cmd != nil {
+			return cmd.Start()
+		}
 	}
 	switch runtime.GOOS {
 	case "darwin":
@@ -173,6 +290,29 @@ func openBrowser(url string) error {
 	return cmd.Start()
 }
 
+var (
+	lookPath = exec.LookPath
+	// wslCmdPath is where cmd.exe lives when Windows drives use the default
+	// /mnt mount point.
+	wslCmdPath = "/mnt/c/Windows/System32/cmd.exe"
+)
+
+// wslOpenCommand hands url to the Windows side of WSL. cmd.exe is not on PATH
+// when interop PATH appending is off, so the default System32 location and
+// wslview (wslu) are tried next; nil means fall back to xdg-open.
+func wslOpenCommand(url string) *exec.Cmd {
+	if path, err := lookPath("cmd.exe"); err == nil {
+		return exec.Command(path, "/c", "start", "", url)
+	}
+	if info, err := os.Stat(wslCmdPath); err == nil && !info.IsDir() {
+		return exec.Command(wslCmdPath, "/c", "start", "", url)
+	}
+	if path, err := lookPath("wslview"); err == nil {
+		return exec.Command(path, url)
+	}
+	return nil
+}
+
 func isWSL() bool {
 	if runtime.GOOS != "linux" {
 		return false
@@ -189,3 +329,20 @@ func isWSL() bool {
 	}
 	return false
 }
+
+// tailscaleHint suggests a fix for the Tailscale setup failures we can identify.
+func tailscaleHint(err error) string {
+	switch {
+	case errors.Is(err, web.ErrTailscaleNotInstalled):
+		return "install Tailscale from https://tailscale.com/download or run without -ts"
+	case errors.Is(err, web.ErrTailscaleNotLoggedIn):
+		return "run `tailscale up` to log in, then restart"
+	case errors.Is(err, web.ErrTailscaleNoFunnel):
+		return "enable Funnel for this node in the Tailscale admin console"
+	case errors.Is(err, web.ErrTailscaleNotReady):
+		return "tailscaled may still be starting; check `tailscale status`"
+	case errors.Is(err, web.ErrTailscaleTimeout):
+		return "the tailscale CLI hung; check that tailscaled is running or raise -ts-timeout"
+	}
+	return ""
+}
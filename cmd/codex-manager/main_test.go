@@ -1,9 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -101,3 +107,95 @@ func setHomeEnv(t *testing.T, home string) {
 	t.Setenv("HOME", home)
 	t.Setenv("USERPROFILE", home)
 }
+
+func TestVersionString(t *testing.T) {
+	got := versionString()
+	if !strings.HasPrefix(got, "codex-manager "+version) {
+		t.Fatalf("unexpected version prefix: %q", got)
+	}
+	if !strings.Contains(got, runtime.Version()) {
+		t.Fatalf("expected go version in %q", got)
+	}
+}
+
+func TestNewLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(true, &buf)
+	logger.Error("rescan failed", "error", errors.New("boom"), "path", "/tmp/sessions")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "rescan failed" || entry["error"] != "boom" || entry["path"] != "/tmp/sessions" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+	if newLogger(false, &buf) != slog.Default() {
+		t.Fatalf("expected the default logger without -log-json")
+	}
+}
+
+func TestInfofUsesDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(newLogger(true, &buf))
+	defer slog.SetDefault(previous)
+
+	infof("would run %s", "tailscale serve")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "would run tailscale serve" || entry["level"] != "INFO" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+}
+
+func TestURLForAddr(t *testing.T) {
+	cases := []struct {
+		addr   string
+		useTLS bool
+		want   string
+	}{
+		{":8080", false, "http://localhost:8080/"},
+		{"127.0.0.1:8443", true, "https://127.0.0.1:8443/"},
+		{"[::1]:8080", true, "https://[::1]:8080/"},
+	}
+	for _, tc := range cases {
+		if got := urlForAddr(tc.addr, tc.useTLS); got != tc.want {
+			t.Fatalf("urlForAddr(%q, %v) = %q, want %q", tc.addr, tc.useTLS, got, tc.want)
+		}
+	}
+}
+
+func TestWSLOpenCommandFallbacks(t *testing.T) {
+	origLookPath, origCmdPath := lookPath, wslCmdPath
+	t.Cleanup(func() { lookPath, wslCmdPath = origLookPath, origCmdPath })
+	found := map[string]string{}
+	lookPath = func(name string) (string, error) {
+		if path, ok := found[name]; ok {
+			return path, nil
+		}
+		return "", exec.ErrNotFound
+	}
+	wslCmdPath = filepath.Join(t.TempDir(), "cmd.exe")
+
+	if cmd := wslOpenCommand("http://localhost:8080/"); cmd != nil {
+		t.Fatalf("expected no command without cmd.exe or wslview, got %v", cmd.Args)
+	}
+	found["wslview"] = "/usr/bin/wslview"
+	if cmd := wslOpenCommand("http://localhost:8080/"); cmd == nil || cmd.Path != "/usr/bin/wslview" {
+		t.Fatalf("expected wslview fallback, got %v", cmd)
+	}
+	if err := os.WriteFile(wslCmdPath, nil, 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if cmd := wslOpenCommand("http://localhost:8080/"); cmd == nil || cmd.Path != wslCmdPath {
+		t.Fatalf("expected System32 cmd.exe, got %v", cmd)
+	}
+	found["cmd.exe"] = "/usr/local/bin/cmd.exe"
+	cmd := wslOpenCommand("http://localhost:8080/")
+	if cmd == nil || cmd.Path != "/usr/local/bin/cmd.exe" || cmd.Args[len(cmd.Args)-1] != "http://localhost:8080/" {
+		t.Fatalf("expected cmd.exe from PATH, got %v", cmd)
+	}
+}
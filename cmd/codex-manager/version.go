@@ -0,0 +1,44 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Stamped at build time, e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.buildDate=2026-01-09T12:00:00Z"
+var (
+	version   = "dev"
+	buildDate = ""
+)
+
+func versionString() string {
+	revision := ""
+	date := buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.time":
+				if date == "" {
+					date = setting.Value
+				}
+			}
+		}
+	}
+
+	parts := []string{"codex-manager " + version}
+	if revision != "" {
+		if len(revision) > 12 {
+			revision = revision[:12]
+		}
+		parts = append(parts, "commit "+revision)
+	}
+	parts = append(parts, runtime.Version())
+	if date != "" {
+		parts = append(parts, "built "+date)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -2,4 +2,13 @@ module codex-manager
 
 go 1.21
 
-require github.com/yuin/goldmark v1.6.0 // indirect
+require (
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/yuin/goldmark v1.6.0
+)
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/net v0.26.0 // indirect
+)
@@ -3,24 +3,82 @@ package config
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"codex-manager/internal/sessions"
 )
 
+// ErrVersion is returned by Parse when -version is requested.
+var ErrVersion = errors.New("version requested")
+
 // Config captures runtime settings for the server.
 type Config struct {
 	SessionsDir    string
 	Addr           string
 	ShareAddr      string
+	ShareBind      string
+	SinglePort     bool
+	ShareCSP       string
+	ShareMode      os.FileMode
 	UseTailscale   bool
+	TailscaleDry   bool
+	TSTimeout      time.Duration
 	UseHTMLBucket  bool
 	NoTrimRequest  bool
+	TrimMarkers    []string
+	TrimLast       bool
+	NoMerge        bool
+	FuseToolCalls  bool
+	SortByTime     bool
 	OpenBrowser    bool
+	BrowserURL     string
 	RescanInterval time.Duration
 	ShareDir       string
+	ArchiveDir     string
+	StateDir       string
 	Theme          int
+	ThemePrimary   string
+	ThemeBg        string
+	ThemeAccent    string
+	DefaultView    string
+	DefaultHeat    string
+	MaxParseSize   int64
+	ParseCache     int64
+	TruncateItems  int
+	EditorCommand  string
+	Location       *time.Location
+	Gzip           bool
+	ShareGzip      bool
+	LogJSON        bool
+	HideEmpty      bool
+	TLSCert        string
+	TLSKey         string
+	PathPattern    sessions.PathPattern
+	PriceTable     string
+	FollowSymlinks bool
+	Ignore         []string
+	ShareRate      int
+	ShareConc      int
+	ReadOnly       bool
+	Lenient        bool
+	Metrics        bool
+
+	SearchDefaultLimit int
+	SearchMaxLimit     int
+	SearchMinQuery     int
+
+	// KeyBindings maps UI actions (see DefaultKeyBindings) to KeyboardEvent.key values.
+	KeyBindings map[string]string
 }
 
 // Parse reads CLI args into a Config.
@@ -28,18 +86,68 @@ func Parse(args []string) (Config, error) {
 	fs := flag.NewFlagSet("codex-manager", flag.ContinueOnError)
 	var cfg Config
 	var showHelp bool
-	fs.StringVar(&cfg.SessionsDir, "sessions-dir", "~/.codex/sessions", "Path to codex sessions directory")
+	var showVersion bool
+	var timezone string
+	var pathPattern string
+	var keys stringList
+	var shareMode string
+	fs.StringVar(&cfg.SessionsDir, "sessions-dir", "~/.codex/sessions", "Path to codex sessions directory (defaults to $CODEX_HOME/sessions when set)")
+	fs.StringVar(&pathPattern, "path-pattern", sessions.DefaultPathPattern, "Directory layout below -sessions-dir, e.g. '{year}-{month}-{day}' or '{account}/{year}/{month}/{day}'")
+	fs.BoolVar(&cfg.FollowSymlinks, "follow-symlinks", false, "Descend into symlinked directories under -sessions-dir")
+	fs.Var(&keys, "key", "Keyboard shortcut as action=key (repeatable or comma-separated); actions: next-session, prev-session, next-user, prev-user, search")
+	fs.Var((*stringList)(&cfg.Ignore), "ignore", "Glob of session files to skip (repeatable or comma-separated); patterns with '/' match the path relative to -sessions-dir, others the file name")
 	fs.StringVar(&cfg.Addr, "addr", ":8080", "HTTP listen address")
 	fs.StringVar(&cfg.ShareAddr, "share-addr", ":8081", "HTTP listen address for share server")
+	fs.StringVar(&cfg.ShareBind, "share-bind", "", "Address the share server actually binds (default: loopback on the -share-addr port unless -ts is set or -share-addr names a host)")
+	fs.StringVar(&cfg.ShareCSP, "share-csp", DefaultShareCSP, "Content-Security-Policy header for served share pages; empty sends none")
+	fs.StringVar(&shareMode, "share-mode", "0600", "Octal permission of local share files, e.g. 0644 to let another web server read them; the share dir also gets x wherever r is set")
+	fs.BoolVar(&cfg.SinglePort, "single-port", false, "Serve shares from the main server under /shared/ instead of a separate share listener")
 	fs.BoolVar(&cfg.UseTailscale, "ts", false, "Use tailscale serve/funnel for share links")
+	fs.BoolVar(&cfg.TailscaleDry, "ts-dry-run", false, "Log the tailscale serve/funnel commands -ts would run without running them")
+	fs.DurationVar(&cfg.TSTimeout, "ts-timeout", 30*time.Second, "How long each tailscale CLI call may run before setup fails")
 	fs.BoolVar(&cfg.UseHTMLBucket, "hb", false, "Use htmlbucket share backend")
 	fs.BoolVar(&cfg.NoTrimRequest, "full", false, "Do not trim user messages to the request marker")
+	cfg.TrimMarkers = []string{sessions.DefaultTrimMarker}
+	fs.Var(&markerList{list: &cfg.TrimMarkers}, "trim-marker", "Marker user messages are trimmed to, keeping the text after it (repeatable; whichever appears is used); an empty value disables marker trimming")
+	fs.BoolVar(&cfg.TrimLast, "trim-last", false, "Trim user messages after the last marker occurrence instead of the first")
+	fs.BoolVar(&cfg.NoMerge, "no-merge", false, "Do not merge consecutive same-type messages (applies to views and search)")
+	fs.BoolVar(&cfg.FuseToolCalls, "fuse-tool-calls", false, "Show each tool output collapsed inside its tool call")
+	fs.BoolVar(&cfg.Lenient, "lenient", false, "Render unrecognized response_item subtypes and envelope types as generic items showing their payload instead of dropping them")
+	fs.BoolVar(&cfg.SortByTime, "sort-by-time", false, "Order session items by timestamp instead of file order (applies to views and search)")
 	fs.BoolVar(&cfg.OpenBrowser, "open-browser", false, "Open the UI in a browser on startup")
+	fs.StringVar(&cfg.BrowserURL, "browser-url", "", "URL -open-browser opens instead of one derived from -addr")
 	fs.DurationVar(&cfg.RescanInterval, "rescan-interval", 2*time.Minute, "How often to rescan sessions directory")
 	fs.StringVar(&cfg.ShareDir, "share-dir", "~/.codex/shares", "Directory to store shared HTML files")
+	fs.StringVar(&cfg.ArchiveDir, "archive-dir", "", "Directory archived sessions are moved to, keeping their date subpath (empty disables archiving)")
+	fs.StringVar(&cfg.StateDir, "state-dir", "", "Directory for persistent UI state such as per-directory last-visit times behind the index's New tags (empty disables)")
+	fs.IntVar(&cfg.ShareRate, "share-rate", 10, "Share creations allowed per minute per client IP; 0 disables the limit")
+	fs.IntVar(&cfg.ShareConc, "share-concurrency", 4, "Shares rendered at once; further requests wait a few seconds for a slot, then get 503 (0 disables the limit)")
+	fs.BoolVar(&cfg.Metrics, "metrics", false, "Serve Prometheus text-format metrics at /metrics")
+	fs.BoolVar(&cfg.ReadOnly, "read-only", false, "Reject sharing, archiving, share revocation, and editor opens with 403 and hide their buttons")
+	fs.Int64Var(&cfg.MaxParseSize, "max-parse-size", 50<<20, "Largest session file (bytes) to render or index; 0 disables the limit")
+	fs.IntVar(&cfg.TruncateItems, "truncate-items", 500, "Render only the first and last N items of longer sessions (?full=1 shows all); 0 disables")
+	fs.Int64Var(&cfg.ParseCache, "parse-cache", sessions.DefaultParseCacheBytes, "Total size (bytes) of session files kept parsed in memory for repeat views and reindexing; 0 disables the cache")
+	fs.StringVar(&cfg.EditorCommand, "editor-command", "", "Command template to open a session cwd in an editor, e.g. 'code {{.Cwd}}' (requires a loopback -addr)")
+	fs.StringVar(&timezone, "tz", "", "IANA timezone for displayed times, e.g. Europe/Berlin (default: system local time)")
+	fs.BoolVar(&cfg.Gzip, "gzip", true, "Gzip HTML/JSON responses from the main UI server")
+	fs.BoolVar(&cfg.ShareGzip, "share-gzip", false, "Gzip responses from the share server")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", "", "TLS certificate file (PEM); with -tls-key, serve HTTPS instead of HTTP")
+	fs.StringVar(&cfg.TLSKey, "tls-key", "", "TLS private key file (PEM) matching -tls-cert")
+	fs.BoolVar(&cfg.HideEmpty, "hide-empty", false, "Leave sessions with no conversation out of day listings")
+	fs.BoolVar(&cfg.LogJSON, "log-json", false, "Write logs as JSON lines (log/slog) instead of plain text")
+	fs.IntVar(&cfg.SearchDefaultLimit, "search-default-limit", 50, "Search results returned when a request gives no limit")
+	fs.IntVar(&cfg.SearchMaxLimit, "search-max-limit", 200, "Largest search limit a request may ask for")
+	fs.IntVar(&cfg.SearchMinQuery, "search-min-query", 2, "Shortest query (in characters) that runs a search")
+	fs.StringVar(&cfg.PriceTable, "price-table", "", "JSON file of per-model prices (USD per 1M tokens) used by /usage cost estimates")
+	fs.StringVar(&cfg.DefaultView, "default-view", "dir", "Index view when / is opened without parameters: date or dir")
+	fs.StringVar(&cfg.DefaultHeat, "default-heat", "1h", "Directory heat window when / is opened without parameters: today, 1h, 7d, a duration like 24h, or <n>d")
 	fs.IntVar(&cfg.Theme, "theme", 3, "Theme palette (1-6): 1=noir-blue, 2=espresso-amber, 3=graphite-teal (default), 4=obsidian-lime, 5=ink-rose, 6=iron-cyan")
+	fs.StringVar(&cfg.ThemePrimary, "theme-primary", "", "Override the theme's text color (#rgb, #rrggbb, rgb()/hsl(), or a color name)")
+	fs.StringVar(&cfg.ThemeBg, "theme-background", "", "Override the theme's background color")
+	fs.StringVar(&cfg.ThemeAccent, "theme-accent", "", "Override the theme's accent (link and highlight) color")
 	fs.BoolVar(&showHelp, "h", false, "Show help")
 	fs.BoolVar(&showHelp, "help", false, "Show help")
+	fs.BoolVar(&showVersion, "version", false, "Print version information and exit")
 	if err := fs.Parse(stripFlagTerminator(args)); err != nil {
 		return Config{}, err
 	}
@@ -47,29 +155,412 @@ func Parse(args []string) (Config, error) {
 		fs.Usage()
 		return Config{}, flag.ErrHelp
 	}
+	if showVersion {
+		return Config{}, ErrVersion
+	}
 
+	if !flagSet(fs, "sessions-dir") {
+		cfg.SessionsDir = defaultSessionsDir()
+	}
 	expanded, err := expandHome(cfg.SessionsDir)
 	if err != nil {
 		return Config{}, err
 	}
 	cfg.SessionsDir = expanded
 
+	if cfg.PriceTable != "" {
+		priceTable, err := expandHome(cfg.PriceTable)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.PriceTable = priceTable
+	}
+
 	shareDir, err := expandHome(cfg.ShareDir)
 	if err != nil {
 		return Config{}, err
 	}
 	cfg.ShareDir = shareDir
 
+	if cfg.ArchiveDir != "" {
+		archiveDir, err := expandHome(cfg.ArchiveDir)
+		if err != nil {
+			return Config{}, err
+		}
+		// Archived files inside the sessions dir would just be indexed again.
+		sessionsAbs, _ := filepath.Abs(cfg.SessionsDir)
+		archiveAbs, _ := filepath.Abs(archiveDir)
+		if rel, err := filepath.Rel(sessionsAbs, archiveAbs); err == nil && filepath.IsLocal(rel) {
+			return Config{}, errors.New("archive-dir must be outside sessions-dir")
+		}
+		cfg.ArchiveDir = archiveDir
+	}
+
+	if cfg.StateDir != "" {
+		if cfg.StateDir, err = expandHome(cfg.StateDir); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return Config{}, errors.New("tls-cert and tls-key must be set together")
+	}
+	if cfg.TLSCert != "" {
+		if cfg.TLSCert, err = expandHome(cfg.TLSCert); err != nil {
+			return Config{}, err
+		}
+		if cfg.TLSKey, err = expandHome(cfg.TLSKey); err != nil {
+			return Config{}, err
+		}
+	}
+
 	if cfg.RescanInterval <= 0 {
 		return Config{}, errors.New("rescan-interval must be positive")
 	}
+	if cfg.TSTimeout <= 0 {
+		return Config{}, errors.New("ts-timeout must be positive")
+	}
+	if cfg.MaxParseSize < 0 {
+		return Config{}, errors.New("max-parse-size cannot be negative")
+	}
+	if cfg.TruncateItems < 0 {
+		return Config{}, errors.New("truncate-items cannot be negative")
+	}
+	if cfg.ParseCache < 0 {
+		return Config{}, errors.New("parse-cache cannot be negative")
+	}
+	if cfg.ShareRate < 0 {
+		return Config{}, errors.New("share-rate cannot be negative")
+	}
+	if cfg.ShareConc < 0 {
+		return Config{}, errors.New("share-concurrency cannot be negative")
+	}
+	if cfg.SearchMaxLimit < 1 {
+		return Config{}, errors.New("search-max-limit must be positive")
+	}
+	if cfg.SearchDefaultLimit < 1 || cfg.SearchDefaultLimit > cfg.SearchMaxLimit {
+		return Config{}, errors.New("search-default-limit must be between 1 and search-max-limit")
+	}
+	if cfg.SearchMinQuery < 1 {
+		return Config{}, errors.New("search-min-query must be positive")
+	}
 	if cfg.Theme < 1 || cfg.Theme > 6 {
 		return Config{}, errors.New("theme must be between 1 and 6")
 	}
+	for _, color := range []struct{ name, value string }{
+		{"theme-primary", cfg.ThemePrimary},
+		{"theme-background", cfg.ThemeBg},
+		{"theme-accent", cfg.ThemeAccent},
+	} {
+		if color.value != "" && !cssColorPattern.MatchString(color.value) {
+			return Config{}, fmt.Errorf("invalid %s %q: use #rgb, #rrggbb, rgb()/hsl(), or a color name", color.name, color.value)
+		}
+	}
+	if cfg.BrowserURL != "" {
+		u, err := url.Parse(cfg.BrowserURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Config{}, fmt.Errorf("invalid browser-url %q: want an absolute http(s) URL", cfg.BrowserURL)
+		}
+	}
+	mode, err := parseShareMode(shareMode)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ShareMode = mode
+	cfg.Location = time.Local
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid tz %q: %w", timezone, err)
+		}
+		cfg.Location = loc
+	}
+	pattern, err := sessions.ParsePathPattern(pathPattern)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.PathPattern = pattern
+	for _, pattern := range cfg.Ignore {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Config{}, fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+	cfg.KeyBindings, err = parseKeyBindings(keys)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.SinglePort && (cfg.UseTailscale || cfg.TailscaleDry) {
+		// Funneling the main port would publish the whole UI, not just shares.
+		return Config{}, errors.New("single-port cannot be combined with -ts or -ts-dry-run")
+	}
+	shareBind, err := shareBindAddr(cfg.ShareBind, cfg.ShareAddr, cfg.UseTailscale)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ShareBind = shareBind
+	if strings.TrimSpace(cfg.EditorCommand) != "" && !IsLoopbackAddr(cfg.Addr) {
+		return Config{}, errors.New("editor-command requires -addr to bind a loopback address (e.g. 127.0.0.1:8080)")
+	}
+
+	return cfg, nil
+}
+
+// ExportConfig captures settings for the export subcommand.
+type ExportConfig struct {
+	SessionsDir    string
+	Format         string
+	NoTrimRequest  bool
+	TrimMarkers    []string
+	TrimLast       bool
+	NoMerge        bool
+	FuseToolCalls  bool
+	SortByTime     bool
+	Lenient        bool
+	PathPattern    sessions.PathPattern
+	FollowSymlinks bool
+	Date           string
+	File           string
+}
+
+// ParseExport reads `export [flags] <date> <file>` arguments into an ExportConfig.
+func ParseExport(args []string) (ExportConfig, error) {
+	fs := flag.NewFlagSet("codex-manager export", flag.ContinueOnError)
+	var cfg ExportConfig
+	var showHelp bool
+	var pathPattern string
+	fs.StringVar(&cfg.SessionsDir, "sessions-dir", "~/.codex/sessions", "Path to codex sessions directory (defaults to $CODEX_HOME/sessions when set)")
+	fs.StringVar(&pathPattern, "path-pattern", sessions.DefaultPathPattern, "Directory layout below -sessions-dir")
+	fs.BoolVar(&cfg.FollowSymlinks, "follow-symlinks", false, "Descend into symlinked directories under -sessions-dir")
+	fs.StringVar(&cfg.Format, "format", "md", "Output format: md or json")
+	fs.BoolVar(&cfg.NoTrimRequest, "full", false, "Do not trim user messages to the request marker")
+	cfg.TrimMarkers = []string{sessions.DefaultTrimMarker}
+	fs.Var(&markerList{list: &cfg.TrimMarkers}, "trim-marker", "Marker user messages are trimmed to, keeping the text after it (repeatable; whichever appears is used); an empty value disables marker trimming")
+	fs.BoolVar(&cfg.TrimLast, "trim-last", false, "Trim user messages after the last marker occurrence instead of the first")
+	fs.BoolVar(&cfg.NoMerge, "no-merge", false, "Do not merge consecutive same-type messages")
+	fs.BoolVar(&cfg.FuseToolCalls, "fuse-tool-calls", false, "Place each tool output under its tool call")
+	fs.BoolVar(&cfg.SortByTime, "sort-by-time", false, "Order session items by timestamp instead of file order")
+	fs.BoolVar(&cfg.Lenient, "lenient", false, "Keep unrecognized response_item subtypes and envelope types as generic items instead of dropping them")
+	fs.BoolVar(&showHelp, "h", false, "Show help")
+	fs.BoolVar(&showHelp, "help", false, "Show help")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: codex-manager export [flags] <yyyy-mm-dd> <file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(stripFlagTerminator(args)); err != nil {
+		return ExportConfig{}, err
+	}
+	if showHelp {
+		fs.Usage()
+		return ExportConfig{}, flag.ErrHelp
+	}
+
+	if !flagSet(fs, "sessions-dir") {
+		cfg.SessionsDir = defaultSessionsDir()
+	}
+	expanded, err := expandHome(cfg.SessionsDir)
+	if err != nil {
+		return ExportConfig{}, err
+	}
+	cfg.SessionsDir = expanded
+	pattern, err := sessions.ParsePathPattern(pathPattern)
+	if err != nil {
+		return ExportConfig{}, err
+	}
+	cfg.PathPattern = pattern
 
+	cfg.Format = strings.ToLower(strings.TrimSpace(cfg.Format))
+	if cfg.Format != "md" && cfg.Format != "json" {
+		return ExportConfig{}, errors.New("format must be md or json")
+	}
+	if fs.NArg() != 2 {
+		return ExportConfig{}, errors.New("export expects <yyyy-mm-dd> <file>")
+	}
+	cfg.Date = fs.Arg(0)
+	cfg.File = fs.Arg(1)
 	return cfg, nil
 }
 
+// shareBindAddr resolves where the share server listens. Without Tailscale the
+// share pages should not be reachable from the LAN by accident, so an
+// all-interfaces -share-addr such as ":8081" binds 127.0.0.1 instead.
+func shareBindAddr(bind, shareAddr string, useTailscale bool) (string, error) {
+	if bind = strings.TrimSpace(bind); bind != "" {
+		return bind, nil
+	}
+	host, port, err := net.SplitHostPort(shareAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid share-addr %q: %w", shareAddr, err)
+	}
+	if host != "" || useTailscale {
+		return shareAddr, nil
+	}
+	return net.JoinHostPort("127.0.0.1", port), nil
+}
+
+// parseShareMode reads an octal permission such as 0644. Modes without owner
+// read/write are rejected since the server itself must write and serve shares,
+// and so are group or world write bits, which would let others swap a share.
+func parseShareMode(value string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(value), "0o"), 8, 32)
+	if err != nil || mode > 0o777 || mode&0o600 != 0o600 || mode&0o022 != 0 {
+		return 0, fmt.Errorf("invalid share-mode %q: want an octal permission with owner read/write and no group/other write, e.g. 0600 or 0644", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// DefaultShareCSP keeps share pages to their own inline styles; the inline
+// script (copy buttons) is blocked so injected markup cannot run either.
+const DefaultShareCSP = "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// DefaultKeyBindings returns the keyboard shortcuts used by the session page
+// unless overridden with -key.
+func DefaultKeyBindings() map[string]string {
+	return map[string]string{
+		"next-session": "n",
+		"prev-session": "p",
+		"next-user":    "j",
+		"prev-user":    "k",
+		"search":       "/",
+	}
+}
+
+// parseKeyBindings applies action=key overrides to the defaults. An empty key
+// disables the action; one key cannot serve two actions.
+func parseKeyBindings(overrides []string) (map[string]string, error) {
+	bindings := DefaultKeyBindings()
+	for _, override := range overrides {
+		action, key, ok := strings.Cut(override, "=")
+		action = strings.TrimSpace(action)
+		if _, known := bindings[action]; !ok || !known {
+			return nil, fmt.Errorf("invalid key binding %q (want action=key with a known action)", override)
+		}
+		bindings[action] = strings.TrimSpace(key)
+	}
+	actions := make([]string, 0, len(bindings))
+	for action := range bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	used := map[string]string{}
+	for _, action := range actions {
+		key := bindings[action]
+		if key == "" {
+			delete(bindings, action)
+			continue
+		}
+		if other, ok := used[key]; ok {
+			return nil, fmt.Errorf("key %q is bound to both %s and %s", key, other, action)
+		}
+		used[key] = action
+	}
+	return bindings, nil
+}
+
+// cssColorPattern accepts hex colors, rgb()/rgba()/hsl()/hsla() with plain
+// numeric arguments, and color names. Nothing that could end the declaration
+// (";", "}", "<") gets through, since the value is emitted into a style block.
+var cssColorPattern = regexp.MustCompile(`^(#([0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})|(rgba?|hsla?)\([0-9a-z.,%/ ]*\)|[a-zA-Z]+)$`)
+
+// stringList is a repeatable flag that also splits comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
+		}
+	}
+	return nil
+}
+
+// markerList is a repeatable flag kept verbatim (markers may contain commas or
+// spaces). The first use replaces the default and an empty value adds nothing,
+// so -trim-marker "" leaves the list empty.
+type markerList struct {
+	list     *[]string
+	replaced bool
+}
+
+func (m *markerList) String() string {
+	if m == nil || m.list == nil {
+		return ""
+	}
+	return strings.Join(*m.list, " | ")
+}
+
+func (m *markerList) Set(value string) error {
+	if !m.replaced {
+		*m.list = nil
+		m.replaced = true
+	}
+	if value != "" {
+		*m.list = append(*m.list, value)
+	}
+	return nil
+}
+
+// IsLoopbackAddr reports whether a listen address only binds loopback interfaces.
+func IsLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// defaultSessionsDir picks the sessions root when -sessions-dir is not given:
+// $CODEX_HOME/sessions, then ~/.codex/sessions if it exists, then
+// $XDG_DATA_HOME/codex/sessions (or ~/.local/share/codex/sessions) if it exists,
+// and finally ~/.codex/sessions.
+func defaultSessionsDir() string {
+	if codexHome := strings.TrimSpace(os.Getenv("CODEX_HOME")); codexHome != "" {
+		return filepath.Join(codexHome, "sessions")
+	}
+	const fallback = "~/.codex/sessions"
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fallback
+	}
+	if dirExists(filepath.Join(home, ".codex", "sessions")) {
+		return fallback
+	}
+	dataHome := strings.TrimSpace(os.Getenv("XDG_DATA_HOME"))
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	if xdg := filepath.Join(dataHome, "codex", "sessions"); dirExists(xdg) {
+		return xdg
+	}
+	return fallback
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// flagSet reports whether name was passed explicitly on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	found := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
 func expandHome(path string) (string, error) {
 	if path == "" {
 		return "", errors.New("sessions-dir cannot be empty")
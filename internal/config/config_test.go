@@ -1,6 +1,14 @@
 package config
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codex-manager/internal/sessions"
+)
 
 func TestParseHTMLBucketFlag(t *testing.T) {
 	cfg, err := Parse([]string{"-hb"})
@@ -11,3 +19,313 @@ func TestParseHTMLBucketFlag(t *testing.T) {
 		t.Fatalf("expected UseHTMLBucket=true")
 	}
 }
+
+func TestParseVersionFlag(t *testing.T) {
+	if _, err := Parse([]string{"-version"}); !errors.Is(err, ErrVersion) {
+		t.Fatalf("expected ErrVersion, got %v", err)
+	}
+}
+
+func TestParseSessionsDirDefaults(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_DATA_HOME", "")
+
+	codexHome := filepath.Join(home, "custom-codex")
+	t.Setenv("CODEX_HOME", codexHome)
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := filepath.Join(codexHome, "sessions"); cfg.SessionsDir != want {
+		t.Fatalf("CODEX_HOME: got %q want %q", cfg.SessionsDir, want)
+	}
+
+	cfg, err = Parse([]string{"-sessions-dir", "/explicit"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.SessionsDir != "/explicit" {
+		t.Fatalf("explicit flag should win, got %q", cfg.SessionsDir)
+	}
+
+	t.Setenv("CODEX_HOME", "")
+	xdg := filepath.Join(home, ".local", "share", "codex", "sessions")
+	if err := os.MkdirAll(xdg, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg, err = Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.SessionsDir != xdg {
+		t.Fatalf("XDG: got %q want %q", cfg.SessionsDir, xdg)
+	}
+
+	legacy := filepath.Join(home, ".codex", "sessions")
+	if err := os.MkdirAll(legacy, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg, err = Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.SessionsDir != legacy {
+		t.Fatalf("~/.codex: got %q want %q", cfg.SessionsDir, legacy)
+	}
+}
+
+func TestParseEditorCommandRequiresLoopback(t *testing.T) {
+	if _, err := Parse([]string{"-editor-command", "code {{.Cwd}}"}); err == nil {
+		t.Fatalf("expected error for editor-command on all interfaces")
+	}
+	cfg, err := Parse([]string{"-editor-command", "code {{.Cwd}}", "-addr", "127.0.0.1:8080"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.EditorCommand != "code {{.Cwd}}" {
+		t.Fatalf("unexpected editor command: %q", cfg.EditorCommand)
+	}
+}
+
+func TestParseTimezone(t *testing.T) {
+	cfg, err := Parse([]string{"-tz", "UTC"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Location == nil || cfg.Location.String() != "UTC" {
+		t.Fatalf("unexpected location: %v", cfg.Location)
+	}
+	if _, err := Parse([]string{"-tz", "Not/AZone"}); err == nil {
+		t.Fatalf("expected invalid tz error")
+	}
+}
+
+func TestParseBrowserURL(t *testing.T) {
+	cfg, err := Parse([]string{"-browser-url", "http://192.168.1.20:8080/"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.BrowserURL != "http://192.168.1.20:8080/" {
+		t.Fatalf("unexpected browser url %q", cfg.BrowserURL)
+	}
+	for _, value := range []string{"localhost:8080", "/sessions", "ftp://host/"} {
+		if _, err := Parse([]string{"-browser-url", value}); err == nil {
+			t.Fatalf("expected error for browser-url %q", value)
+		}
+	}
+}
+
+func TestParseShareMode(t *testing.T) {
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.ShareMode != 0o600 {
+		t.Fatalf("expected default 0600, got %o", cfg.ShareMode)
+	}
+	cfg, err = Parse([]string{"-share-mode", "0644"})
+	if err != nil || cfg.ShareMode != 0o644 {
+		t.Fatalf("expected 0644, got %o (%v)", cfg.ShareMode, err)
+	}
+	for _, value := range []string{"644x", "01777", "0400", "rw-r--r--", "0666", "0620", "0602"} {
+		if _, err := Parse([]string{"-share-mode", value}); err == nil {
+			t.Fatalf("expected error for share-mode %q", value)
+		}
+	}
+}
+
+func TestParseIgnorePatterns(t *testing.T) {
+	cfg, err := Parse([]string{"-ignore", "*fixture*,2025/*/*/*.jsonl", "-ignore", "tmp-*"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []string{"*fixture*", "2025/*/*/*.jsonl", "tmp-*"}
+	if strings.Join(cfg.Ignore, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected ignore patterns: %v", cfg.Ignore)
+	}
+	if _, err := Parse([]string{"-ignore", "[bad"}); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+}
+
+func TestParseTrimMarkers(t *testing.T) {
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(cfg.TrimMarkers) != 1 || cfg.TrimMarkers[0] != sessions.DefaultTrimMarker || cfg.TrimLast {
+		t.Fatalf("unexpected defaults: %q last=%v", cfg.TrimMarkers, cfg.TrimLast)
+	}
+	cfg, err = Parse([]string{"-trim-marker", "## Request, please:", "-trim-marker", "## Ask:", "-trim-last"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if strings.Join(cfg.TrimMarkers, "|") != "## Request, please:|## Ask:" || !cfg.TrimLast {
+		t.Fatalf("unexpected markers: %q last=%v", cfg.TrimMarkers, cfg.TrimLast)
+	}
+	cfg, err = Parse([]string{"-trim-marker", ""})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(cfg.TrimMarkers) != 0 {
+		t.Fatalf("expected an empty marker to clear the list, got %q", cfg.TrimMarkers)
+	}
+}
+
+func TestParseThemeColors(t *testing.T) {
+	cfg, err := Parse([]string{"-theme-primary", "#fafafa", "-theme-background", "rgb(10, 12, 14)", "-theme-accent", "tomato"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg.ThemePrimary != "#fafafa" || cfg.ThemeBg != "rgb(10, 12, 14)" || cfg.ThemeAccent != "tomato" {
+		t.Fatalf("unexpected colors: %q %q %q", cfg.ThemePrimary, cfg.ThemeBg, cfg.ThemeAccent)
+	}
+	for _, bad := range []string{"#12", "red; background: url(x)", "red}</style>", "rgb(1,2,3));"} {
+		if _, err := Parse([]string{"-theme-accent", bad}); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParseShareBind(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{nil, "127.0.0.1:8081"},
+		{[]string{"-share-addr", ":9000"}, "127.0.0.1:9000"},
+		{[]string{"-ts"}, ":8081"},
+		{[]string{"-share-addr", "0.0.0.0:8081"}, "0.0.0.0:8081"},
+		{[]string{"-share-bind", ":8081"}, ":8081"},
+	}
+	for _, tc := range cases {
+		cfg, err := Parse(tc.args)
+		if err != nil {
+			t.Fatalf("Parse(%v): %v", tc.args, err)
+		}
+		if cfg.ShareBind != tc.want {
+			t.Fatalf("Parse(%v): share bind %q want %q", tc.args, cfg.ShareBind, tc.want)
+		}
+	}
+	if _, err := Parse([]string{"-share-addr", "8081"}); err == nil {
+		t.Fatalf("expected invalid share-addr error")
+	}
+}
+
+func TestParseSearchLimits(t *testing.T) {
+	cfg, err := Parse([]string{"-search-default-limit", "100", "-search-max-limit", "1000", "-search-min-query", "3"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.SearchDefaultLimit != 100 || cfg.SearchMaxLimit != 1000 || cfg.SearchMinQuery != 3 {
+		t.Fatalf("unexpected search limits: %+v", cfg)
+	}
+	for _, args := range [][]string{
+		{"-search-max-limit", "0"},
+		{"-search-default-limit", "300"},
+		{"-search-min-query", "0"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("Parse(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArchiveDir(t *testing.T) {
+	base := t.TempDir()
+	sessionsDir := filepath.Join(base, "sessions")
+	archiveDir := filepath.Join(base, "archive")
+	cfg, err := Parse([]string{"-sessions-dir", sessionsDir, "-archive-dir", archiveDir})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.ArchiveDir != archiveDir {
+		t.Fatalf("unexpected archive dir: %q", cfg.ArchiveDir)
+	}
+	for _, dir := range []string{sessionsDir, filepath.Join(sessionsDir, "archive"), filepath.Join(sessionsDir, "..old")} {
+		if _, err := Parse([]string{"-sessions-dir", sessionsDir, "-archive-dir", dir}); err == nil {
+			t.Fatalf("expected error for archive dir %q inside sessions dir", dir)
+		}
+	}
+}
+
+func TestParseShareConcurrency(t *testing.T) {
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.ShareConc != 4 {
+		t.Fatalf("expected default share concurrency 4, got %d", cfg.ShareConc)
+	}
+	if _, err := Parse([]string{"-share-concurrency", "-1"}); err == nil {
+		t.Fatalf("expected error for negative share-concurrency")
+	}
+}
+
+func TestParseStateDir(t *testing.T) {
+	cfg, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.StateDir != "" {
+		t.Fatalf("expected state dir disabled by default, got %q", cfg.StateDir)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home dir")
+	}
+	cfg, err = Parse([]string{"-state-dir", "~/.codex/manager-state"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.StateDir != filepath.Join(home, ".codex/manager-state") {
+		t.Fatalf("expected ~ to expand, got %q", cfg.StateDir)
+	}
+}
+
+func TestParseTLSRequiresCertAndKey(t *testing.T) {
+	cfg, err := Parse([]string{"-tls-cert", "cert.pem", "-tls-key", "key.pem"})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.TLSCert != "cert.pem" || cfg.TLSKey != "key.pem" {
+		t.Fatalf("unexpected tls files: %q %q", cfg.TLSCert, cfg.TLSKey)
+	}
+	for _, args := range [][]string{{"-tls-cert", "cert.pem"}, {"-tls-key", "key.pem"}} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("Parse(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseSinglePortRejectsTailscale(t *testing.T) {
+	cfg, err := Parse([]string{"-single-port"})
+	if err != nil || !cfg.SinglePort {
+		t.Fatalf("Parse(-single-port): %v, SinglePort=%v", err, cfg.SinglePort)
+	}
+	for _, args := range [][]string{{"-single-port", "-ts"}, {"-single-port", "-ts-dry-run"}} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("Parse(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseKeyBindings(t *testing.T) {
+	cfg, err := Parse([]string{"-key", "next-session=ArrowRight,prev-session=ArrowLeft", "-key", "search="})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.KeyBindings["next-session"] != "ArrowRight" || cfg.KeyBindings["prev-session"] != "ArrowLeft" || cfg.KeyBindings["next-user"] != "j" {
+		t.Fatalf("unexpected bindings: %v", cfg.KeyBindings)
+	}
+	if _, ok := cfg.KeyBindings["search"]; ok {
+		t.Fatalf("expected empty key to disable search: %v", cfg.KeyBindings)
+	}
+	for _, args := range [][]string{{"-key", "jump=x"}, {"-key", "next-session"}, {"-key", "next-session=j"}} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("Parse(%v): expected error", args)
+		}
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"embed"
 	"html/template"
 	"io"
+	"strings"
 	"time"
 )
 
 //go:embed templates/*.html
 var templatesFS embed.FS
 
+//go:embed static
+var staticFS embed.FS
+
 // Renderer loads and executes HTML templates.
 type Renderer struct {
 	templates *template.Template
@@ -32,6 +36,18 @@ func (r *Renderer) Execute(w io.Writer, name string, data any) error {
 	return r.templates.ExecuteTemplate(w, name, data)
 }
 
+// Static returns an embedded asset (favicon, icon, manifest) by file name.
+func Static(name string) ([]byte, bool) {
+	if strings.Contains(name, "/") {
+		return nil, false
+	}
+	data, err := staticFS.ReadFile("static/" + name)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
 func formatTime(t time.Time) string {
 	if t.IsZero() {
 		return ""
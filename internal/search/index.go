@@ -1,10 +1,12 @@
 package search
 
 import (
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"codex-manager/internal/sessions"
 )
@@ -14,8 +16,28 @@ const (
 	maxLimit      = 200
 	snippetRadius = 60
 	snippetMax    = 180
+
+	minSnippetRadius = 10
+	maxSnippetRadius = 500
+	minSnippetMax    = 40
+	maxSnippetMax    = 2000
 )
 
+// Options tunes a search beyond the query text. Zero values use the defaults.
+type Options struct {
+	Limit         int
+	PreviewRadius int
+	PreviewMax    int
+	// File restricts the search to one session, keyed "yyyy/mm/dd/name".
+	// Results then come back in file order rather than newest first.
+	File string
+	// Raw also matches the original JSONL line of each item, which holds
+	// fields such as call_id and tool names that never reach the content.
+	Raw bool
+	// Offset skips that many matches, counted after ordering, for paging.
+	Offset int
+}
+
 // Result describes a single search match.
 type Result struct {
 	Date      string `json:"date"`
@@ -26,6 +48,12 @@ type Result struct {
 	Line      int    `json:"line"`
 	Role      string `json:"role"`
 	Preview   string `json:"preview"`
+	// Raw reports that only the raw JSONL line matched (see Options.Raw).
+	Raw bool `json:"raw,omitempty"`
+	// TimestampRaw is the item's timestamp as written in the session file;
+	// Time is that value parsed, set only when it is valid RFC 3339.
+	TimestampRaw string     `json:"timestampRaw,omitempty"`
+	Time         *time.Time `json:"time,omitempty"`
 
 	sortTime time.Time
 }
@@ -41,24 +69,68 @@ type entry struct {
 	role      string
 	content   string
 	lower     string
+	// raw is the item's source line, searched only when Options.Raw is set.
+	// It is lowercased per search rather than stored twice, since raw
+	// searches are rare and the lines are the bulk of the index.
+	raw string
+	// timestampRaw and parsedTime back Result.TimestampRaw and Result.Time.
+	timestampRaw string
+	parsedTime   time.Time
 }
 
 type fileIndex struct {
 	size    int64
 	modTime time.Time
 	entries []entry
+	// tools counts tool calls by name.
+	tools map[string]int
 }
 
 // Index stores a searchable snapshot of sessions.
 type Index struct {
-	mu      sync.RWMutex
-	files   map[string]fileIndex
-	ordered []entry
+	mu          sync.RWMutex
+	files       map[string]fileIndex
+	ordered     []entry
+	byKey       map[string][]entry
+	tools       []ToolCount
+	maxFileSize int64
+	limit       int
+	maxLimit    int
 }
 
 // NewIndex creates an empty search index.
 func NewIndex() *Index {
-	return &Index{files: map[string]fileIndex{}}
+	return &Index{files: map[string]fileIndex{}, limit: defaultLimit, maxLimit: maxLimit}
+}
+
+// Counts returns how many files and searchable entries are indexed.
+func (idx *Index) Counts() (files, entries int) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.files), len(idx.ordered)
+}
+
+// SetLimits changes the result count used when Options.Limit is zero and the
+// cap applied to larger requests. Non-positive values keep the current setting.
+func (idx *Index) SetLimits(defaultCount, maxCount int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if maxCount > 0 {
+		idx.maxLimit = maxCount
+	}
+	if defaultCount > 0 {
+		idx.limit = defaultCount
+	}
+	if idx.limit > idx.maxLimit {
+		idx.limit = idx.maxLimit
+	}
+}
+
+// SetMaxFileSize skips indexing files larger than size bytes; 0 disables the limit.
+func (idx *Index) SetMaxFileSize(size int64) {
+	idx.mu.Lock()
+	idx.maxFileSize = size
+	idx.mu.Unlock()
 }
 
 // RefreshFrom rebuilds entries for new or changed files in the sessions index.
@@ -71,11 +143,15 @@ func (idx *Index) RefreshFrom(sessionsIdx *sessions.Index) error {
 
 	idx.mu.RLock()
 	existing := idx.files
+	maxFileSize := idx.maxFileSize
 	idx.mu.RUnlock()
 
 	next := make(map[string]fileIndex, len(files))
 	toParse := make([]sessions.SessionFile, 0)
 	for _, file := range files {
+		if maxFileSize > 0 && file.Size > maxFileSize {
+			continue
+		}
 		key := file.Path
 		if meta, ok := existing[key]; ok && meta.size == file.Size && meta.modTime.Equal(file.ModTime) {
 			next[key] = meta
@@ -86,7 +162,7 @@ func (idx *Index) RefreshFrom(sessionsIdx *sessions.Index) error {
 
 	var firstErr error
 	for _, file := range toParse {
-		entries, err := buildEntries(file)
+		entries, tools, err := buildEntries(file)
 		if err != nil {
 			if firstErr == nil {
 				firstErr = err
@@ -96,21 +172,31 @@ func (idx *Index) RefreshFrom(sessionsIdx *sessions.Index) error {
 			}
 			continue
 		}
-		next[file.Path] = fileIndex{size: file.Size, modTime: file.ModTime, entries: entries}
+		next[file.Path] = fileIndex{size: file.Size, modTime: file.ModTime, entries: entries, tools: tools}
 	}
 
 	ordered := make([]entry, 0)
+	byKey := make(map[string][]entry, len(next))
 	for _, date := range dates {
 		for _, file := range sessionsIdx.SessionsByDate(date) {
 			if meta, ok := next[file.Path]; ok {
 				ordered = append(ordered, meta.entries...)
+				// file= names the session the page shows, which is the
+				// newest of any files sharing its date and name.
+				if !sessionsIdx.Shadowed(file) {
+					byKey[path.Join(date.Path(), file.Name)] = meta.entries
+				}
 			}
 		}
 	}
 
+	tools := aggregateTools(next)
+
 	idx.mu.Lock()
 	idx.files = next
 	idx.ordered = ordered
+	idx.byKey = byKey
+	idx.tools = tools
 	idx.mu.Unlock()
 
 	return firstErr
@@ -118,28 +204,46 @@ func (idx *Index) RefreshFrom(sessionsIdx *sessions.Index) error {
 
 // Search returns the first N matches for the query.
 func (idx *Index) Search(query string, limit int) []Result {
-	q := strings.TrimSpace(query)
-	if q == "" {
+	return idx.SearchWithOptions(query, Options{Limit: limit})
+}
+
+// SearchWithOptions returns matches for the query using the given options.
+// See ParseQuery for the query syntax.
+func (idx *Index) SearchWithOptions(query string, opts Options) []Result {
+	q := ParseQuery(query)
+	if q.Empty() {
 		return nil
 	}
+	radius, max := previewBounds(opts.PreviewRadius, opts.PreviewMax)
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	limit := opts.Limit
 	if limit <= 0 {
-		limit = defaultLimit
+		limit = idx.limit
 	}
-	if limit > maxLimit {
-		limit = maxLimit
+	if limit > idx.maxLimit {
+		limit = idx.maxLimit
 	}
-	lower := strings.ToLower(q)
 
-	idx.mu.RLock()
-	defer idx.mu.RUnlock()
+	candidates := idx.ordered
+	if opts.File != "" {
+		candidates = idx.byKey[opts.File]
+	}
 
-	results := make([]Result, 0, limit)
-	for _, item := range idx.ordered {
-		matchIndex := strings.Index(item.lower, lower)
-		if matchIndex == -1 {
+	results := make([]Result, 0, min(limit, len(candidates)))
+	for _, item := range candidates {
+		text, matchedRaw := item.content, false
+		matchIndex, matchLen, ok := q.match(item.lower)
+		if !ok && opts.Raw && item.raw != "" {
+			text, matchedRaw = item.raw, true
+			matchIndex, matchLen, ok = q.match(strings.ToLower(item.raw))
+		}
+		if !ok {
 			continue
 		}
-		preview := makePreview(item.content, matchIndex, len(q))
+		preview := makePreview(text, matchIndex, matchLen, radius, max)
 		results = append(results, Result{
 			Date:      item.date,
 			Timestamp: item.timestamp,
@@ -149,23 +253,39 @@ func (idx *Index) Search(query string, limit int) []Result {
 			Line:      item.line,
 			Role:      item.role,
 			Preview:   preview,
+			Raw:       matchedRaw,
 			sortTime:  item.sortTime,
+
+			TimestampRaw: item.timestampRaw,
 		})
+		if !item.parsedTime.IsZero() {
+			ts := item.parsedTime
+			results[len(results)-1].Time = &ts
+		}
 	}
 
-	sort.SliceStable(results, func(i, j int) bool {
-		return results[i].sortTime.After(results[j].sortTime)
-	})
+	if opts.File != "" {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].Line < results[j].Line
+		})
+	} else {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].sortTime.After(results[j].sortTime)
+		})
+	}
+	if opts.Offset > 0 {
+		results = results[min(opts.Offset, len(results)):]
+	}
 	if len(results) > limit {
 		results = results[:limit]
 	}
 	return results
 }
 
-func buildEntries(file sessions.SessionFile) ([]entry, error) {
-	session, err := sessions.ParseSession(file.Path)
+func buildEntries(file sessions.SessionFile) ([]entry, map[string]int, error) {
+	session, err := sessions.ParseSessionCached(file.Path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	entries := make([]entry, 0, len(session.Items))
@@ -178,12 +298,50 @@ func buildEntries(file sessions.SessionFile) ([]entry, error) {
 		cwd = file.Meta.Cwd
 	}
 	cwd = sessions.NormalizeCwd(cwd)
+	if session.Meta != nil {
+		if instructions := strings.TrimSpace(session.Meta.Instructions); instructions != "" {
+			timestamp := parseTimestamp(session.Meta.Timestamp, file.ModTime)
+			raw := strings.TrimSpace(session.Meta.Timestamp)
+			parsed, _ := parseRFC3339(raw)
+			entries = append(entries, entry{
+				date:      dateLabel,
+				timestamp: formatTimestamp(timestamp),
+				sortTime:  timestamp,
+				cwd:       cwd,
+				path:      datePath,
+				file:      file.Name,
+				line:      session.InstructionsLine,
+				role:      "system",
+				content:   instructions,
+				lower:     strings.ToLower(instructions),
+
+				timestampRaw: raw,
+				parsedTime:   parsed,
+			})
+		}
+	}
+	var tools map[string]int
 	for _, item := range session.Items {
+		if item.ToolName != "" {
+			if tools == nil {
+				tools = map[string]int{}
+			}
+			tools[item.ToolName]++
+		}
+		if item.Hidden {
+			continue
+		}
 		content := strings.TrimSpace(item.Content)
-		if content == "" {
+		if output := strings.TrimSpace(item.Output); output != "" {
+			content += "\n\n" + output
+		}
+		raw := strings.TrimSpace(item.Raw)
+		if content == "" && raw == "" {
 			continue
 		}
 		timestamp := parseTimestamp(item.Timestamp, file.ModTime)
+		rawTimestamp := strings.TrimSpace(item.Timestamp)
+		parsed, _ := parseRFC3339(rawTimestamp)
 		entries = append(entries, entry{
 			date:      dateLabel,
 			timestamp: formatTimestamp(timestamp),
@@ -195,12 +353,43 @@ func buildEntries(file sessions.SessionFile) ([]entry, error) {
 			role:      item.Role,
 			content:   content,
 			lower:     strings.ToLower(content),
+			raw:       raw,
+
+			timestampRaw: rawTimestamp,
+			parsedTime:   parsed,
 		})
 	}
-	return entries, nil
+	return entries, tools, nil
 }
 
-func makePreview(content string, matchIndex int, queryLen int) string {
+// previewBounds clamps requested snippet sizes, falling back to the defaults
+// for zero values. The radius never exceeds half the max so the match stays visible.
+func previewBounds(radius, max int) (int, int) {
+	if radius <= 0 {
+		radius = snippetRadius
+	}
+	if max <= 0 {
+		max = snippetMax
+	}
+	radius = clamp(radius, minSnippetRadius, maxSnippetRadius)
+	max = clamp(max, minSnippetMax, maxSnippetMax)
+	if radius > max/2 {
+		radius = max / 2
+	}
+	return radius, max
+}
+
+func clamp(value, low, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
+
+func makePreview(content string, matchIndex int, queryLen int, radius int, max int) string {
 	cleaned := strings.ReplaceAll(content, "\r", " ")
 	cleaned = strings.ReplaceAll(cleaned, "\n", " ")
 	cleaned = strings.TrimSpace(cleaned)
@@ -208,16 +397,21 @@ func makePreview(content string, matchIndex int, queryLen int) string {
 		return ""
 	}
 	if matchIndex < 0 || matchIndex >= len(cleaned) || queryLen <= 0 {
-		return truncate(cleaned, snippetMax)
+		return truncate(cleaned, max)
 	}
-	start := matchIndex - snippetRadius
+	start := matchIndex - radius
 	if start < 0 {
 		start = 0
 	}
-	end := matchIndex + queryLen + snippetRadius
+	end := matchIndex + queryLen + radius
+	if end-start > max {
+		end = start + max
+	}
 	if end > len(cleaned) {
 		end = len(cleaned)
 	}
+	start = runeFloor(cleaned, start)
+	end = runeFloor(cleaned, end)
 	snippet := strings.TrimSpace(cleaned[start:end])
 	if start > 0 {
 		snippet = "..." + snippet
@@ -233,9 +427,24 @@ func truncate(value string, max int) string {
 		return value
 	}
 	if max <= 3 {
-		return value[:max]
+		return value[:runeFloor(value, max)]
 	}
-	return value[:max-3] + "..."
+	return value[:runeFloor(value, max-3)] + "..."
+}
+
+// runeFloor backs a byte offset off to the nearest rune boundary at or before it,
+// so slicing never splits a multi-byte character.
+func runeFloor(value string, index int) int {
+	if index >= len(value) {
+		return len(value)
+	}
+	if index < 0 {
+		return 0
+	}
+	for index > 0 && !utf8.RuneStart(value[index]) {
+		index--
+	}
+	return index
 }
 
 func parseTimestamp(value string, fallback time.Time) time.Time {
@@ -243,10 +452,7 @@ func parseTimestamp(value string, fallback time.Time) time.Time {
 	if value == "" {
 		return fallback
 	}
-	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
-		return ts
-	}
-	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+	if ts, ok := parseRFC3339(value); ok {
 		return ts
 	}
 	if ts, err := time.Parse("2006-01-02 15:04:05", value); err == nil {
@@ -255,6 +461,16 @@ func parseTimestamp(value string, fallback time.Time) time.Time {
 	return fallback
 }
 
+func parseRFC3339(value string) (time.Time, bool) {
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return ts, true
+	}
+	if ts, err := time.Parse(time.RFC3339, value); err == nil {
+		return ts, true
+	}
+	return time.Time{}, false
+}
+
 func formatTimestamp(ts time.Time) string {
 	if ts.IsZero() {
 		return ""
@@ -1,10 +1,14 @@
 package search
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"codex-manager/internal/sessions"
 )
@@ -74,3 +78,410 @@ func writeSessionFile(t *testing.T, baseDir, relPath string, lines []string) {
 		t.Fatalf("write file: %v", err)
 	}
 }
+
+func TestSearchPreviewOptions(t *testing.T) {
+	baseDir := t.TempDir()
+	long := strings.Repeat("a", 300) + " needle " + strings.Repeat("b", 300)
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"t1","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":"` + long + `"}]}}`,
+	})
+
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	results := searchIdx.Search("needle", 10)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	defaultLen := len(results[0].Preview)
+
+	results = searchIdx.SearchWithOptions("needle", Options{PreviewRadius: 200, PreviewMax: 600})
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	preview := results[0].Preview
+	if len(preview) <= defaultLen {
+		t.Fatalf("expected longer preview than %d, got %d", defaultLen, len(preview))
+	}
+	if !strings.Contains(preview, "needle") || !strings.HasPrefix(preview, "...") || !strings.HasSuffix(preview, "...") {
+		t.Fatalf("unexpected preview: %q", preview)
+	}
+
+	results = searchIdx.SearchWithOptions("needle", Options{PreviewRadius: 400, PreviewMax: 50})
+	preview = strings.TrimSuffix(strings.TrimPrefix(results[0].Preview, "..."), "...")
+	if len(preview) > 50 || !strings.Contains(preview, "needle") {
+		t.Fatalf("expected clamped preview containing match, got %q", results[0].Preview)
+	}
+}
+
+func TestMakePreviewMultiByte(t *testing.T) {
+	content := strings.Repeat("日本語🙂", 40) + "target" + strings.Repeat("漢字😀", 40)
+	matchIndex := strings.Index(content, "target")
+	for radius := 10; radius < 20; radius++ {
+		preview := makePreview(content, matchIndex, len("target"), radius, 180)
+		if !utf8.ValidString(preview) {
+			t.Fatalf("radius %d: invalid utf-8 in preview %q", radius, preview)
+		}
+		if !strings.Contains(preview, "target") {
+			t.Fatalf("radius %d: preview lost match: %q", radius, preview)
+		}
+	}
+	for max := 4; max < 20; max++ {
+		if got := truncate(content, max); !utf8.ValidString(got) {
+			t.Fatalf("max %d: invalid utf-8 in truncation %q", max, got)
+		}
+	}
+}
+
+func TestIndexSearchInstructions(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"2024-01-02T10:00:00Z","type":"session_meta","payload":{"id":"abc","cwd":"/tmp","instructions":"Always prefer tabs over spaces"}}`,
+		`{"timestamp":"2024-01-02T10:00:01Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"Hello world"}]}}`,
+	})
+
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	results := searchIdx.Search("tabs over", 10)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].Role != "system" || results[0].Line != 1 {
+		t.Fatalf("expected system result on line 1, got role %q line %d", results[0].Role, results[0].Line)
+	}
+}
+
+func TestSearchSingleFile(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/a.jsonl", []string{
+		`{"timestamp":"2024-01-02T10:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"needle one"}]}}`,
+		`{"timestamp":"2024-01-02T10:00:05Z","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":"needle two"}]}}`,
+	})
+	writeSessionFile(t, baseDir, "2024/01/03/b.jsonl", []string{
+		`{"timestamp":"2024-01-03T10:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"needle elsewhere"}]}}`,
+	})
+
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	if results := searchIdx.Search("needle", 10); len(results) != 3 {
+		t.Fatalf("expected 3 global results, got %d", len(results))
+	}
+	results := searchIdx.SearchWithOptions("needle", Options{Limit: 10, File: "2024/01/02/a.jsonl"})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results in a.jsonl, got %d", len(results))
+	}
+	if results[0].Line != 1 || results[1].Line != 2 {
+		t.Fatalf("expected file order, got lines %d, %d", results[0].Line, results[1].Line)
+	}
+	if results := searchIdx.SearchWithOptions("needle", Options{File: "2024/01/02/missing.jsonl"}); len(results) != 0 {
+		t.Fatalf("expected no results for unknown file, got %d", len(results))
+	}
+}
+
+func TestSearchSingleFileSkipsShadowedFile(t *testing.T) {
+	baseDir := t.TempDir()
+	now := time.Now()
+	for account, text := range map[string]string{"work": "needle old", "home": "needle new"} {
+		rel := account + "/2024-01-02/same.jsonl"
+		writeSessionFile(t, baseDir, rel, []string{
+			`{"timestamp":"2024-01-02T10:00:00Z","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"` + text + `"}]}}`,
+		})
+		modTime := now.Add(-time.Hour)
+		if account == "home" {
+			modTime = now
+		}
+		if err := os.Chtimes(filepath.Join(baseDir, filepath.FromSlash(rel)), modTime, modTime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+	pattern, err := sessions.ParsePathPattern("{account}/{year}-{month}-{day}")
+	if err != nil {
+		t.Fatalf("parse pattern: %v", err)
+	}
+	idx := sessions.NewIndex(baseDir)
+	idx.SetPathPattern(pattern)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	if results := searchIdx.Search("needle", 10); len(results) != 2 {
+		t.Fatalf("expected both files in global results, got %d", len(results))
+	}
+	// The session page opens the newest file, so file= must search that one.
+	results := searchIdx.SearchWithOptions("needle", Options{File: "2024/01/02/same.jsonl"})
+	if len(results) != 1 || !strings.Contains(results[0].Preview, "new") {
+		t.Fatalf("expected only the newest same-name file, got %+v", results)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want []string
+	}{
+		{`rate limit`, []string{"rate", "limit"}},
+		{`"Rate Limit"`, []string{"rate limit"}},
+		{`retry "rate limit" backoff`, []string{"retry", "rate limit", "backoff"}},
+		{`foo"bar baz"`, []string{"foo", "bar baz"}},
+		{`"unterminated phrase`, []string{"unterminated phrase"}},
+		{`"" spaced   out `, []string{"spaced", "out"}},
+		{`   `, nil},
+	}
+	for _, tc := range cases {
+		got := ParseQuery(tc.raw).Terms
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") || len(got) != len(tc.want) {
+			t.Fatalf("ParseQuery(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestParseQueryExclusions(t *testing.T) {
+	cases := []struct {
+		raw     string
+		terms   []string
+		exclude []string
+	}{
+		{`config -deprecated`, []string{"config"}, []string{"deprecated"}},
+		{`-"rate limit" retry`, []string{"retry"}, []string{"rate limit"}},
+		{`e-mail - x`, []string{"e-mail", "-", "x"}, nil},
+		{`--flag`, nil, []string{"-flag"}},
+		{`-Old`, nil, []string{"old"}},
+	}
+	for _, tc := range cases {
+		q := ParseQuery(tc.raw)
+		if strings.Join(q.Terms, "|") != strings.Join(tc.terms, "|") || strings.Join(q.Exclude, "|") != strings.Join(tc.exclude, "|") {
+			t.Fatalf("ParseQuery(%q) = %q / %q, want %q / %q", tc.raw, q.Terms, q.Exclude, tc.terms, tc.exclude)
+		}
+	}
+}
+
+func TestSearchPhrasesAndTerms(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"t1","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"We hit the rate limit again"}]}}`,
+		`{"timestamp":"t2","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":"Limit the retry rate to one per second"}]}}`,
+	})
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		lines []int
+	}{
+		{`rate limit`, []int{1, 2}},
+		{`"rate limit"`, []int{1}},
+		{`"rate limit" again`, []int{1}},
+		{`"retry rate" limit`, []int{2}},
+		{`"rate limit" second`, nil},
+		{`rate -again`, []int{2}},
+		{`limit -"rate limit"`, []int{2}},
+		{`rate -second -again`, nil},
+		{`-again`, nil},
+	}
+	for _, tc := range cases {
+		results := searchIdx.SearchWithOptions(tc.query, Options{File: "2024/01/02/session.jsonl"})
+		if len(results) != len(tc.lines) {
+			t.Fatalf("%s: expected %d results, got %d", tc.query, len(tc.lines), len(results))
+		}
+		for i, result := range results {
+			if result.Line != tc.lines[i] {
+				t.Fatalf("%s: expected line %d, got %d", tc.query, tc.lines[i], result.Line)
+			}
+		}
+	}
+}
+
+func TestSearchRawLines(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"t1","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"List the files"}]}}`,
+		`{"timestamp":"t2","type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{\"command\":[\"ls\"]}","call_id":"call_XyZ789"}}`,
+	})
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	if results := searchIdx.SearchWithOptions("call_xyz789", Options{}); len(results) != 0 {
+		t.Fatalf("expected call_id to stay out of the default search, got %d results", len(results))
+	}
+	results := searchIdx.SearchWithOptions("call_xyz789", Options{Raw: true})
+	if len(results) != 1 || results[0].Line != 2 || !results[0].Raw {
+		t.Fatalf("expected one raw match on line 2, got %+v", results)
+	}
+	if !strings.Contains(results[0].Preview, "call_XyZ789") {
+		t.Fatalf("expected preview from the raw line, got %q", results[0].Preview)
+	}
+	results = searchIdx.SearchWithOptions("files", Options{Raw: true})
+	if len(results) != 1 || results[0].Raw {
+		t.Fatalf("expected a content match not flagged raw, got %+v", results)
+	}
+}
+
+func TestSearchResultTimes(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"2024-01-02T23:59:58.250+02:00","type":"response_item","payload":{"type":"message","role":"user","content":[{"type":"text","text":"needle parsed"}]}}`,
+		`{"timestamp":"t2","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":"needle opaque"}]}}`,
+		`{"type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":"needle missing"}]}}`,
+	})
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	byLine := map[int]Result{}
+	for _, result := range searchIdx.SearchWithOptions("needle", Options{File: "2024/01/02/session.jsonl"}) {
+		byLine[result.Line] = result
+	}
+	parsed := byLine[1]
+	if parsed.TimestampRaw != "2024-01-02T23:59:58.250+02:00" || parsed.Time == nil {
+		t.Fatalf("expected raw and parsed timestamps, got %+v", parsed)
+	}
+	if want := time.Date(2024, 1, 2, 21, 59, 58, 250e6, time.UTC); !parsed.Time.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, parsed.Time)
+	}
+	if opaque := byLine[2]; opaque.TimestampRaw != "t2" || opaque.Time != nil {
+		t.Fatalf("expected only the raw timestamp for an invalid value, got %+v", opaque)
+	}
+	if missing := byLine[3]; missing.TimestampRaw != "" || missing.Time != nil {
+		t.Fatalf("expected no timestamps, got %+v", missing)
+	}
+
+	data, err := json.Marshal(byLine[3])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if strings.Contains(string(data), "timestampRaw") || strings.Contains(string(data), `"time"`) {
+		t.Fatalf("expected empty timestamps to be omitted, got %s", data)
+	}
+}
+
+func TestSearchSkipsHiddenReasoning(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", []string{
+		`{"timestamp":"t1","type":"response_item","payload":{"type":"reasoning","summary":[],"encrypted_content":"gAAAAABzZWNyZXQ="}}`,
+	})
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+	for _, query := range []string{"reasoning hidden", "gAAAAABzZWNyZXQ"} {
+		if results := searchIdx.SearchWithOptions(query, Options{Raw: true}); len(results) != 0 {
+			t.Fatalf("%q: expected encrypted reasoning to stay out of the index, got %+v", query, results)
+		}
+	}
+}
+
+func TestSearchOffsetCountsMatches(t *testing.T) {
+	baseDir := t.TempDir()
+	var lines []string
+	for i := 0; i < 6; i++ {
+		text := "filler"
+		if i%2 == 0 {
+			text = fmt.Sprintf("needle %d", i)
+		}
+		lines = append(lines, fmt.Sprintf(`{"timestamp":"2024-01-02T00:00:0%dZ","type":"response_item","payload":{"type":"message","role":"assistant","content":[{"type":"text","text":%q}]}}`, i, text))
+	}
+	writeSessionFile(t, baseDir, "2024/01/02/session.jsonl", lines)
+	sessions.SetMergeConsecutiveEnabled(false)
+	defer sessions.SetMergeConsecutiveEnabled(true)
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	all := searchIdx.SearchWithOptions("needle", Options{})
+	if len(all) != 3 {
+		t.Fatalf("expected 3 matches, got %d", len(all))
+	}
+	page := searchIdx.SearchWithOptions("needle", Options{Limit: 1, Offset: 1})
+	if len(page) != 1 || page[0].Line != all[1].Line {
+		t.Fatalf("expected the second match, got %+v", page)
+	}
+	if rest := searchIdx.SearchWithOptions("needle", Options{Offset: 5}); len(rest) != 0 {
+		t.Fatalf("expected nothing past the last match, got %d", len(rest))
+	}
+}
+
+func TestIndexTools(t *testing.T) {
+	baseDir := t.TempDir()
+	writeSessionFile(t, baseDir, "2024/01/02/a.jsonl", []string{
+		`{"type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{}","call_id":"c1"}}`,
+		`{"type":"response_item","payload":{"type":"function_call_output","call_id":"c1","output":"ok"}}`,
+		`{"type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{}","call_id":"c2"}}`,
+		`{"type":"response_item","payload":{"type":"custom_tool_call","name":"apply_patch","input":"x","call_id":"c3"}}`,
+	})
+	writeSessionFile(t, baseDir, "2024/01/03/b.jsonl", []string{
+		`{"type":"response_item","payload":{"type":"function_call","name":"shell","arguments":"{}","call_id":"c1"}}`,
+		`{"type":"response_item","payload":{"type":"web_search_call","action":{"query":"go"}}}`,
+	})
+	idx := sessions.NewIndex(baseDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	searchIdx := NewIndex()
+	if err := searchIdx.RefreshFrom(idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+	want := []ToolCount{
+		{Name: "shell", Calls: 3, Sessions: 2},
+		{Name: "apply_patch", Calls: 1, Sessions: 1},
+		{Name: "web_search", Calls: 1, Sessions: 1},
+	}
+	got := searchIdx.Tools()
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
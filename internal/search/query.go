@@ -0,0 +1,96 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search string. Quoted segments are kept as literal
+// phrases and bare words become separate terms; an entry matches only when it
+// contains every term and none of the exclusions.
+type Query struct {
+	// Terms are lowercased substrings, phrases included with inner spacing intact.
+	Terms []string
+	// Exclude holds terms written with a leading "-" (e.g. -deprecated or
+	// -"rate limit"); any entry containing one is dropped.
+	Exclude []string
+}
+
+// ParseQuery splits raw into terms. An unterminated quote runs to the end of
+// the input; empty quotes are ignored. A "-" only negates when it starts a
+// word or directly precedes a quote, so "e-mail" and a lone "-" stay literal.
+func ParseQuery(raw string) Query {
+	var q Query
+	var current strings.Builder
+	inQuote := false
+	negate := false
+	emit := func(term string) {
+		if term != "" {
+			term = strings.ToLower(term)
+			if negate {
+				q.Exclude = append(q.Exclude, term)
+			} else {
+				q.Terms = append(q.Terms, term)
+			}
+		}
+		negate = false
+		current.Reset()
+	}
+	for _, r := range raw {
+		switch {
+		case r == '"' && inQuote:
+			emit(current.String())
+			inQuote = false
+		case r == '"':
+			if current.Len() > 0 {
+				emit(current.String())
+			}
+			inQuote = true
+		case inQuote:
+			current.WriteRune(r)
+		case unicode.IsSpace(r):
+			if negate && current.Len() == 0 {
+				negate = false
+				current.WriteRune('-')
+			}
+			emit(current.String())
+		case r == '-' && current.Len() == 0 && !negate:
+			negate = true
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if !inQuote && negate && current.Len() == 0 {
+		negate = false
+		current.WriteRune('-')
+	}
+	emit(current.String())
+	return q
+}
+
+// Empty reports whether the query has nothing to match. Exclusions alone
+// match nothing: they only filter the results of positive terms.
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0
+}
+
+// match reports whether lower (already lowercased) contains every term and no
+// exclusion, and returns the earliest term occurrence for the preview.
+func (q Query) match(lower string) (int, int, bool) {
+	for _, term := range q.Exclude {
+		if strings.Contains(lower, term) {
+			return 0, 0, false
+		}
+	}
+	first, length := -1, 0
+	for _, term := range q.Terms {
+		index := strings.Index(lower, term)
+		if index == -1 {
+			return 0, 0, false
+		}
+		if first == -1 || index < first {
+			first, length = index, len(term)
+		}
+	}
+	return first, length, first != -1
+}
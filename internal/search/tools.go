@@ -0,0 +1,43 @@
+package search
+
+import "sort"
+
+// ToolCount is how often one tool was called across the indexed sessions.
+type ToolCount struct {
+	Name     string `json:"name"`
+	Calls    int    `json:"calls"`
+	Sessions int    `json:"sessions"`
+}
+
+// Tools returns tool call counts from the last refresh, most called first.
+func (idx *Index) Tools() []ToolCount {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return append([]ToolCount(nil), idx.tools...)
+}
+
+func aggregateTools(files map[string]fileIndex) []ToolCount {
+	byName := map[string]*ToolCount{}
+	for _, file := range files {
+		for name, calls := range file.tools {
+			count, ok := byName[name]
+			if !ok {
+				count = &ToolCount{Name: name}
+				byName[name] = count
+			}
+			count.Calls += calls
+			count.Sessions++
+		}
+	}
+	out := make([]ToolCount, 0, len(byName))
+	for _, count := range byName {
+		out = append(out, *count)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Calls != out[j].Calls {
+			return out[i].Calls > out[j].Calls
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
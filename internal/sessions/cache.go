@@ -0,0 +1,144 @@
+package sessions
+
+import (
+	"container/list"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// parseCacheMaxEntries bounds the number of sessions kept by ParseSessionCached.
+	parseCacheMaxEntries = 128
+	// DefaultParseCacheBytes bounds the summed file size of cached sessions.
+	DefaultParseCacheBytes = 256 << 20
+)
+
+// parseCache is an LRU of parsed sessions. An entry is reused only while the
+// file's size and modtime and the parse settings match what it was built from.
+type parseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+}
+
+type parseCacheEntry struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	settings parseSettings
+	session  *Session
+}
+
+// parseSettings captures the Set*Enabled flags that change ParseSession output.
+type parseSettings struct {
+	trim, merge, fuse, sortByTime bool
+	trimLast, lenient             bool
+	trimMarkers                   string
+}
+
+func currentParseSettings() parseSettings {
+	return parseSettings{
+		trim:        trimUserRequestEnabled,
+		merge:       mergeConsecutiveEnabled,
+		fuse:        fuseToolCallsEnabled,
+		sortByTime:  sortByTimeEnabled,
+		trimLast:    trimFromLastMarker,
+		lenient:     lenientParseEnabled,
+		trimMarkers: strings.Join(trimUserRequestMarkers, "\x00"),
+	}
+}
+
+func newParseCache(maxEntries int, maxBytes int64) *parseCache {
+	return &parseCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+var defaultParseCache = newParseCache(parseCacheMaxEntries, DefaultParseCacheBytes)
+
+// SetParseCacheBytes bounds the total file size ParseSessionCached keeps
+// parsed in memory; 0 disables the cache.
+func SetParseCacheBytes(maxBytes int64) {
+	defaultParseCache.setMaxBytes(maxBytes)
+}
+
+// ParseSessionCached is ParseSession backed by a small LRU, for callers that
+// only read the result. The returned Session is shared and must not be modified.
+func ParseSessionCached(path string) (*Session, error) {
+	return defaultParseCache.parse(path)
+}
+
+func (c *parseCache) parse(path string) (*Session, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := currentParseSettings()
+	if session, ok := c.get(path, info, settings); ok {
+		return session, nil
+	}
+	session, err := ParseSession(path)
+	if err != nil {
+		return nil, err
+	}
+	c.put(&parseCacheEntry{path: path, size: info.Size(), modTime: info.ModTime(), settings: settings, session: session})
+	return session, nil
+}
+
+func (c *parseCache) get(path string, info os.FileInfo, settings parseSettings) (*Session, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[path]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*parseCacheEntry)
+	if entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) || entry.settings != settings {
+		c.remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.session, true
+}
+
+func (c *parseCache) put(entry *parseCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.size > c.maxBytes {
+		return
+	}
+	if elem, ok := c.entries[entry.path]; ok {
+		c.remove(elem)
+	}
+	c.entries[entry.path] = c.order.PushFront(entry)
+	c.bytes += entry.size
+	c.evict()
+}
+
+func (c *parseCache) setMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxBytes = maxBytes
+	c.evict()
+}
+
+// evict drops least recently used entries until both limits hold.
+func (c *parseCache) evict() {
+	for c.order.Len() > 0 && (c.order.Len() > c.maxEntries || c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *parseCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*parseCacheEntry)
+	delete(c.entries, entry.path)
+	c.bytes -= entry.size
+}
@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCacheSession(t *testing.T, path, text string, modTime time.Time) {
+	t.Helper()
+	data := "{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"" + text + "\"}]}}\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+}
+
+func TestParseCacheInvalidatesOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jsonl")
+	modTime := time.Date(2026, 1, 9, 1, 0, 0, 0, time.UTC)
+	writeCacheSession(t, path, "one", modTime)
+	cache := newParseCache(4, 1<<20)
+
+	first, err := cache.parse(path)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	again, err := cache.parse(path)
+	if err != nil || again != first {
+		t.Fatalf("expected the cached session on a repeat parse (err %v)", err)
+	}
+
+	writeCacheSession(t, path, "two", modTime.Add(time.Second))
+	changed, err := cache.parse(path)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if changed == first || changed.Items[0].Content != "two" {
+		t.Fatalf("expected a fresh parse after the file changed, got %q", changed.Items[0].Content)
+	}
+
+	SetSortByTimeEnabled(true)
+	defer SetSortByTimeEnabled(false)
+	if resorted, _ := cache.parse(path); resorted == changed {
+		t.Fatalf("expected a fresh parse after parse settings changed")
+	}
+}
+
+func TestParseCacheEvicts(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2026, 1, 9, 1, 0, 0, 0, time.UTC)
+	paths := make([]string, 3)
+	for i, name := range []string{"a", "b", "c"} {
+		paths[i] = filepath.Join(dir, name+".jsonl")
+		writeCacheSession(t, paths[i], name, modTime)
+	}
+
+	cache := newParseCache(2, 1<<20)
+	for _, path := range paths {
+		if _, err := cache.parse(path); err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+	}
+	if _, ok := cache.entries[paths[0]]; ok || len(cache.entries) != 2 {
+		t.Fatalf("expected the oldest entry evicted by count, have %d entries", len(cache.entries))
+	}
+
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	cache.setMaxBytes(info.Size())
+	if len(cache.entries) != 1 || cache.bytes != info.Size() {
+		t.Fatalf("expected one entry within the byte limit, have %d (%d bytes)", len(cache.entries), cache.bytes)
+	}
+	if _, ok := cache.entries[paths[2]]; !ok {
+		t.Fatalf("expected the most recently used entry to survive")
+	}
+
+	cache.setMaxBytes(0)
+	if _, err := cache.parse(paths[0]); err != nil || len(cache.entries) != 0 {
+		t.Fatalf("expected nothing cached when disabled (err %v)", err)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package sessions
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID identifies a directory independent of the path used to reach it.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+func fileIDOf(_ string, info os.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,22 @@
+//go:build windows
+
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// fileID identifies a directory independent of the path used to reach it.
+// Windows has no inode numbers in os.FileInfo, so the resolved path stands in.
+type fileID struct {
+	path string
+}
+
+func fileIDOf(path string, _ os.FileInfo) (fileID, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileID{}, false
+	}
+	return fileID{path: resolved}, true
+}
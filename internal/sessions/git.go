@@ -0,0 +1,76 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitInfo describes the git checkout that contains a working directory.
+type GitInfo struct {
+	// Repo is the name of the repository's top-level directory.
+	Repo string
+	// Branch is the checked-out branch; empty for a detached HEAD.
+	Branch string
+}
+
+// DetectGit walks up from cwd to the nearest .git entry and reads HEAD. It
+// reports false when cwd is not inside a repository (or no longer exists).
+func DetectGit(cwd string) (GitInfo, bool) {
+	if cwd == "" || cwd == UnknownCwd || !filepath.IsAbs(cwd) {
+		return GitInfo{}, false
+	}
+	dir := filepath.Clean(cwd)
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			gitDir, ok := resolveGitDir(gitPath, info)
+			if !ok {
+				return GitInfo{}, false
+			}
+			return GitInfo{
+				Repo:   filepath.Base(dir),
+				Branch: readGitBranch(gitDir),
+			}, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return GitInfo{}, false
+		}
+		dir = parent
+	}
+}
+
+// resolveGitDir handles both a .git directory and the "gitdir: ..." file
+// used by worktrees and submodules.
+func resolveGitDir(gitPath string, info os.FileInfo) (string, bool) {
+	if info.IsDir() {
+		return gitPath, true
+	}
+	data, err := os.ReadFile(gitPath)
+	if err != nil {
+		return "", false
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", false
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(gitPath), target)
+	}
+	return target, true
+}
+
+func readGitBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref:")
+	if !ok {
+		// Detached HEAD holds a bare commit hash.
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(ref), "refs/heads/")
+}
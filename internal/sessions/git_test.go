@@ -0,0 +1,59 @@
+package sessions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectGit(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	branchRepo := filepath.Join(root, "app")
+	writeFile(filepath.Join(branchRepo, ".git", "HEAD"), "ref: refs/heads/feature/x\n")
+	nested := filepath.Join(branchRepo, "internal", "pkg")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	detached := filepath.Join(root, "detached")
+	writeFile(filepath.Join(detached, ".git", "HEAD"), "0123456789abcdef0123456789abcdef01234567\n")
+
+	worktree := filepath.Join(root, "wt")
+	writeFile(filepath.Join(root, "gitdirs", "wt", "HEAD"), "ref: refs/heads/hotfix\n")
+	writeFile(filepath.Join(worktree, ".git"), "gitdir: ../gitdirs/wt\n")
+
+	plain := filepath.Join(root, "plain")
+	if err := os.MkdirAll(plain, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	cases := []struct {
+		cwd  string
+		want GitInfo
+		ok   bool
+	}{
+		{branchRepo, GitInfo{Repo: "app", Branch: "feature/x"}, true},
+		{nested, GitInfo{Repo: "app", Branch: "feature/x"}, true},
+		{detached, GitInfo{Repo: "detached"}, true},
+		{worktree, GitInfo{Repo: "wt", Branch: "hotfix"}, true},
+		{plain, GitInfo{}, false},
+		{UnknownCwd, GitInfo{}, false},
+		{"relative/path", GitInfo{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := DetectGit(tc.cwd)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("DetectGit(%q) = %+v, %v; want %+v, %v", tc.cwd, got, ok, tc.want, tc.ok)
+		}
+	}
+}
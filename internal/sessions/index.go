@@ -2,6 +2,7 @@ package sessions
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path"
@@ -36,25 +37,52 @@ type SessionFile struct {
 	Size    int64
 	ModTime time.Time
 	Meta    *SessionMeta
+	// Summary is the first meaningful user message, shortened for listings.
+	Summary string
+	// Empty is set when the file has no renderable items, e.g. Codex was
+	// opened and closed without a conversation.
+	Empty bool
+	// Kind classifies the session from its head (see SessionKind).
+	Kind SessionKind
 }
 
 // Index stores a snapshot of sessions on disk.
 type Index struct {
 	baseDir string
+	pattern PathPattern
+	follow  bool
+	ignore  []string
 	mu      sync.RWMutex
 	byDate  map[DateKey][]SessionFile
 	byName  map[string]SessionFile
 	byCwd   map[string][]SessionFile
-	updated time.Time
+	byID    map[string]SessionFile
+	git     map[string]GitInfo
+	// files holds every indexed file by its slash path below baseDir; byName
+	// maps date/name to the newest of the files sharing it.
+	files map[string]SessionFile
+	// duplicates lists the date/name keys more than one file shares.
+	duplicates []string
+	// latestCli is the newest CliVersion seen in the last refresh.
+	latestCli string
+	updated   time.Time
+	// missing is set while the last refresh found no directory at baseDir.
+	missing bool
+	// scanTook is how long the last successful refresh ran.
+	scanTook time.Duration
 }
 
 // NewIndex creates an empty index.
 func NewIndex(baseDir string) *Index {
 	return &Index{
 		baseDir: baseDir,
+		pattern: defaultPathPattern,
 		byDate:  map[DateKey][]SessionFile{},
 		byName:  map[string]SessionFile{},
 		byCwd:   map[string][]SessionFile{},
+		byID:    map[string]SessionFile{},
+		git:     map[string]GitInfo{},
+		files:   map[string]SessionFile{},
 	}
 }
 
@@ -63,6 +91,75 @@ func (idx *Index) BaseDir() string {
 	return idx.baseDir
 }
 
+// SetPathPattern changes the directory layout Refresh expects below the base dir.
+func (idx *Index) SetPathPattern(pattern PathPattern) {
+	if pattern.re == nil {
+		pattern = defaultPathPattern
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.pattern = pattern
+}
+
+// PathPattern returns the directory layout Refresh expects below the base dir.
+func (idx *Index) PathPattern() PathPattern {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.pattern
+}
+
+// SetFollowSymlinks makes Refresh descend into symlinked directories and index
+// symlinked files. Directories reached twice (e.g. via a link loop) are skipped.
+func (idx *Index) SetFollowSymlinks(follow bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.follow = follow
+}
+
+// SetIgnorePatterns skips files whose path relative to the base dir matches any
+// of the globs. Patterns containing "/" match the whole relative path; others
+// match just the file name.
+func (idx *Index) SetIgnorePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ignore pattern %q: %w", pattern, err)
+		}
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.ignore = append([]string(nil), patterns...)
+	return nil
+}
+
+func ignored(patterns []string, rel string) bool {
+	name := path.Base(rel)
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// BaseDirMissing reports whether the last refresh failed because the sessions
+// directory does not exist. It clears once a refresh finds the directory.
+func (idx *Index) BaseDirMissing() bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.missing
+}
+
+// LastScanDuration returns how long the last successful Refresh took.
+func (idx *Index) LastScanDuration() time.Duration {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.scanTook
+}
+
 // LastUpdated returns when Refresh last succeeded.
 func (idx *Index) LastUpdated() time.Time {
 	idx.mu.RLock()
@@ -72,24 +169,41 @@ func (idx *Index) LastUpdated() time.Time {
 
 // Refresh rescans the sessions directory.
 func (idx *Index) Refresh() error {
+	_, err := idx.RefreshChanges()
+	return err
+}
+
+// RefreshChanges rescans the sessions directory and returns the paths (relative
+// to the base dir, slash-separated) of files that were not present in the
+// previous snapshot. The initial scan reports no additions.
+func (idx *Index) RefreshChanges() ([]string, error) {
 	if idx.baseDir == "" {
-		return errors.New("sessions base directory is empty")
+		return nil, errors.New("sessions base directory is empty")
 	}
+	started := time.Now()
 	if _, err := os.Stat(idx.baseDir); err != nil {
-		return err
+		idx.mu.Lock()
+		idx.missing = errors.Is(err, fs.ErrNotExist)
+		idx.mu.Unlock()
+		return nil, err
 	}
 
-	byDate := map[DateKey][]SessionFile{}
-	byName := map[string]SessionFile{}
-	byCwd := map[string][]SessionFile{}
+	idx.mu.RLock()
+	pattern := idx.pattern
+	follow := idx.follow
+	ignore := idx.ignore
+	previous := idx.files
+	idx.mu.RUnlock()
 
-	walkErr := filepath.WalkDir(idx.baseDir, func(fullPath string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if d.IsDir() {
-			return nil
-		}
+	// The walk only matches names; stat and head parsing run afterwards on a
+	// worker pool since each can block for a while on network filesystems.
+	type candidate struct {
+		rel   string
+		file  SessionFile
+		entry fs.DirEntry
+	}
+	var candidates []candidate
+	walkErr := walkFiles(idx.baseDir, follow, func(fullPath string, d fs.DirEntry) error {
 		if !strings.HasSuffix(d.Name(), ".jsonl") {
 			return nil
 		}
@@ -98,43 +212,102 @@ func (idx *Index) Refresh() error {
 		if err != nil {
 			return err
 		}
-		parts := strings.Split(filepath.ToSlash(rel), "/")
-		if len(parts) != 4 {
+		rel = filepath.ToSlash(rel)
+		if ignored(ignore, rel) {
 			return nil
 		}
-		date, ok := ParseDate(parts[0], parts[1], parts[2])
+		date, name, ok := pattern.Match(rel)
 		if !ok {
 			return nil
 		}
+		candidates = append(candidates, candidate{rel: rel, file: SessionFile{Date: date, Name: name, Path: fullPath}, entry: d})
+		return nil
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
 
-		info, err := d.Info()
+	statErrs := make([]error, len(candidates))
+	forEachParallel(len(candidates), func(i int) {
+		info, err := candidates[i].entry.Info()
 		if err != nil {
-			return err
+			statErrs[i] = err
+			return
 		}
+		candidates[i].file.Size = info.Size()
+		candidates[i].file.ModTime = info.ModTime()
+	})
+	for _, err := range statErrs {
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		meta, err := ParseSessionMeta(fullPath)
+	indexed := make(map[string]SessionFile, len(candidates))
+	order := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		indexed[c.rel] = c.file
+		order = append(order, c.rel)
+	}
+
+	// Unchanged files keep their parsed head instead of being read again.
+	var stale []string
+	for _, key := range order {
+		file := indexed[key]
+		if prev, ok := previous[key]; ok && prev.Path == file.Path && prev.Size == file.Size && prev.ModTime.Equal(file.ModTime) {
+			file.Meta = prev.Meta
+			file.Summary = prev.Summary
+			file.Empty = prev.Empty
+			file.Kind = prev.Kind
+			indexed[key] = file
+			continue
+		}
+		stale = append(stale, key)
+	}
+	parsed := make([]SessionFile, len(stale))
+	forEachParallel(len(stale), func(i int) {
+		file := indexed[stale[i]]
+		meta, err := ParseSessionMeta(file.Path)
 		if err != nil {
 			meta = nil
 		}
+		file.Meta = meta
+		head, err := parseSessionHead(file.Path)
+		file.Summary = head.summary
+		file.Empty = err == nil && !head.hasItems
+		file.Kind = head.kind
+		parsed[i] = file
+	})
+	for i, key := range stale {
+		indexed[key] = parsed[i]
+	}
 
-		file := SessionFile{
-			Date:    date,
-			Name:    parts[3],
-			Path:    fullPath,
-			Size:    info.Size(),
-			ModTime: info.ModTime(),
-			Meta:    meta,
+	byName := map[string]SessionFile{}
+	byDate := map[DateKey][]SessionFile{}
+	byCwd := map[string][]SessionFile{}
+	byID := map[string]SessionFile{}
+	shared := map[string]int{}
+	for _, key := range order {
+		file := indexed[key]
+		// Routes address a session by date and name, so when two files share
+		// both (e.g. under different {account} dirs) Lookup resolves to the
+		// newest. Both stay in the listings, cwd index, and search, each with
+		// its own meta.
+		nameKey := path.Join(file.Date.Path(), file.Name)
+		shared[nameKey]++
+		if existing, ok := byName[nameKey]; !ok || newerSessionFile(file, existing) {
+			byName[nameKey] = file
 		}
-
-		byDate[date] = append(byDate[date], file)
-		byName[path.Join(date.Path(), file.Name)] = file
+		byDate[file.Date] = append(byDate[file.Date], file)
 		cwd := CwdForFile(file)
 		byCwd[cwd] = append(byCwd[cwd], file)
-		return nil
-	})
-
-	if walkErr != nil {
-		return walkErr
+		// A copied or resumed file can repeat another's id; the newest wins,
+		// matching how duplicate date+name keys are resolved.
+		if file.Meta != nil && file.Meta.ID != "" {
+			if existing, ok := byID[file.Meta.ID]; !ok || newerSessionFile(file, existing) {
+				byID[file.Meta.ID] = file
+			}
+		}
 	}
 
 	for dateKey, files := range byDate {
@@ -147,12 +320,139 @@ func (idx *Index) Refresh() error {
 		byDate[dateKey] = files
 	}
 
+	var duplicates []string
+	for nameKey, count := range shared {
+		if count > 1 {
+			duplicates = append(duplicates, nameKey)
+		}
+	}
+	sort.Strings(duplicates)
+
+	latestCli := ""
+	for _, file := range indexed {
+		if version := CliVersionForFile(file); version != "" && (latestCli == "" || CompareCliVersions(version, latestCli) > 0) {
+			latestCli = version
+		}
+	}
+
+	// Detect git once per cwd per refresh rather than per session or request.
+	git := map[string]GitInfo{}
+	for cwd := range byCwd {
+		if info, ok := DetectGit(cwd); ok {
+			git[cwd] = info
+		}
+	}
+
 	idx.mu.Lock()
+	var added []string
+	if !idx.updated.IsZero() {
+		for key := range indexed {
+			if _, ok := idx.files[key]; !ok {
+				added = append(added, key)
+			}
+		}
+		sort.Strings(added)
+	}
 	idx.byDate = byDate
 	idx.byName = byName
+	idx.files = indexed
+	idx.duplicates = duplicates
 	idx.byCwd = byCwd
+	idx.byID = byID
+	idx.git = git
+	idx.latestCli = latestCli
 	idx.updated = time.Now()
+	idx.scanTook = idx.updated.Sub(started)
+	idx.missing = false
 	idx.mu.Unlock()
+	return added, nil
+}
+
+// refreshWorkers bounds how many files RefreshChanges stats or parses at once.
+const refreshWorkers = 8
+
+// forEachParallel calls fn(0..n-1) on up to refreshWorkers goroutines and
+// waits for all of them. fn must only touch state for its own index.
+func forEachParallel(n int, fn func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(n, refreshWorkers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// newerSessionFile reports whether a should replace b for the same date/name
+// key: the more recently modified file wins, ties go to the smaller path.
+func newerSessionFile(a, b SessionFile) bool {
+	if !a.ModTime.Equal(b.ModTime) {
+		return a.ModTime.After(b.ModTime)
+	}
+	return a.Path < b.Path
+}
+
+// walkFiles calls visit for every non-directory entry below root. Without
+// follow it is a plain filepath.WalkDir, which does not traverse symlinks.
+func walkFiles(root string, follow bool, visit func(fullPath string, d fs.DirEntry) error) error {
+	if !follow {
+		return filepath.WalkDir(root, func(fullPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return visit(fullPath, d)
+		})
+	}
+	return walkFollow(root, map[fileID]bool{}, visit)
+}
+
+func walkFollow(dir string, visited map[fileID]bool, visit func(fullPath string, d fs.DirEntry) error) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if id, ok := fileIDOf(dir, info); ok {
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		if entry.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Stat(fullPath)
+			if err != nil {
+				// Dangling links are skipped rather than failing the whole scan.
+				continue
+			}
+			entry = fs.FileInfoToDirEntry(target)
+		}
+		if entry.IsDir() {
+			if err := walkFollow(fullPath, visited, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := visit(fullPath, entry); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -181,6 +481,33 @@ func (idx *Index) SessionsByDate(date DateKey) []SessionFile {
 	return out
 }
 
+// Latest returns the most recently modified session, limited to cwd when it is
+// non-empty. Each date's files are already sorted newest first.
+func (idx *Index) Latest(cwd string) (SessionFile, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var candidates []SessionFile
+	if cwd != "" {
+		candidates = idx.byCwd[cwd]
+	} else {
+		for _, files := range idx.byDate {
+			if len(files) > 0 {
+				candidates = append(candidates, files[0])
+			}
+		}
+	}
+	var latest SessionFile
+	found := false
+	for _, file := range candidates {
+		if !found || file.ModTime.After(latest.ModTime) {
+			latest = file
+			found = true
+		}
+	}
+	return latest, found
+}
+
 // Cwds returns sorted working directory keys.
 func (idx *Index) Cwds() []string {
 	idx.mu.RLock()
@@ -213,6 +540,45 @@ func (idx *Index) SessionsByCwd(cwd string) []SessionFile {
 	return out
 }
 
+// Git returns the repository and branch detected for cwd at the last refresh.
+func (idx *Index) Git(cwd string) (GitInfo, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	info, ok := idx.git[NormalizeCwd(cwd)]
+	return info, ok
+}
+
+// LatestCliVersion returns the newest Codex CLI version among indexed sessions.
+func (idx *Index) LatestCliVersion() string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.latestCli
+}
+
+// FileCount returns the number of indexed session files.
+func (idx *Index) FileCount() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.files)
+}
+
+// DuplicateNames returns the date/name keys that more than one file shares,
+// sorted. Only the newest of each is reachable by date and name.
+func (idx *Index) DuplicateNames() []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return append([]string(nil), idx.duplicates...)
+}
+
+// Shadowed reports whether a newer file with the same date and name hides
+// file from Lookup.
+func (idx *Index) Shadowed(file SessionFile) bool {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	newest, ok := idx.byName[path.Join(file.Date.Path(), file.Name)]
+	return ok && newest.Path != file.Path
+}
+
 // CwdCounts returns session counts per working directory.
 func (idx *Index) CwdCounts() map[string]int {
 	idx.mu.RLock()
@@ -224,7 +590,8 @@ func (idx *Index) CwdCounts() map[string]int {
 	return counts
 }
 
-// Lookup returns the file for a date+name.
+// Lookup returns the file for a date+name; when several files share both,
+// the most recently modified one.
 func (idx *Index) Lookup(date DateKey, filename string) (SessionFile, bool) {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
@@ -232,6 +599,14 @@ func (idx *Index) Lookup(date DateKey, filename string) (SessionFile, bool) {
 	return file, ok
 }
 
+// LookupID returns the file whose session metadata carries id.
+func (idx *Index) LookupID(id string) (SessionFile, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	file, ok := idx.byID[id]
+	return file, ok
+}
+
 func ParseDate(year, month, day string) (DateKey, bool) {
 	if len(year) != 4 || len(month) != 2 || len(day) != 2 {
 		return DateKey{}, false
@@ -242,6 +617,17 @@ func ParseDate(year, month, day string) (DateKey, bool) {
 	return DateKey{Year: year, Month: month, Day: day}, true
 }
 
+// ParseDateLabel parses yyyy-mm-dd (or yyyy/mm/dd) into a DateKey.
+func ParseDateLabel(value string) (DateKey, bool) {
+	parts := strings.FieldsFunc(strings.TrimSpace(value), func(r rune) bool {
+		return r == '-' || r == '/'
+	})
+	if len(parts) != 3 {
+		return DateKey{}, false
+	}
+	return ParseDate(parts[0], parts[1], parts[2])
+}
+
 func isDigits(value string) bool {
 	for _, ch := range value {
 		if ch < '0' || ch > '9' {
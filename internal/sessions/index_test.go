@@ -1,8 +1,10 @@
 package sessions
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -51,3 +53,342 @@ func TestIndexRefreshAndLookup(t *testing.T) {
 		t.Fatalf("unexpected path: %s", lookup.Path)
 	}
 }
+
+func TestIndexRefreshChangesReportsNewFiles(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "2026", "01", "09")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.jsonl"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	idx := NewIndex(base)
+	added, err := idx.RefreshChanges()
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if len(added) != 0 {
+		t.Fatalf("expected initial scan to report nothing, got %v", added)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.jsonl"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	added, err = idx.RefreshChanges()
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if len(added) != 1 || added[0] != "2026/01/09/b.jsonl" {
+		t.Fatalf("unexpected additions: %v", added)
+	}
+}
+
+func TestIndexRefreshPathPattern(t *testing.T) {
+	base := t.TempDir()
+	files := []string{
+		filepath.Join(base, "work", "2026-01-09", "a.jsonl"),
+		filepath.Join(base, "home", "2026-01-10", "b.jsonl"),
+		filepath.Join(base, "2026", "01", "11", "ignored.jsonl"),
+	}
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(file, []byte("{}\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	pattern, err := ParsePathPattern("{account}/{year}-{month}-{day}")
+	if err != nil {
+		t.Fatalf("parse pattern: %v", err)
+	}
+	idx := NewIndex(base)
+	idx.SetPathPattern(pattern)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	dates := idx.Dates()
+	if len(dates) != 2 {
+		t.Fatalf("expected 2 dates, got %v", dates)
+	}
+	date, _ := ParseDate("2026", "01", "09")
+	file, ok := idx.Lookup(date, "a.jsonl")
+	if !ok || file.Path != files[0] {
+		t.Fatalf("expected lookup of a.jsonl at %s, got %+v", files[0], file)
+	}
+}
+
+func TestIndexRefreshDuplicateNames(t *testing.T) {
+	base := t.TempDir()
+	now := time.Now()
+	for account, cwd := range map[string]string{"work": "/proj/work", "home": "/proj/home"} {
+		file := filepath.Join(base, account, "2026-01-09", "same.jsonl")
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		line := `{"type":"session_meta","payload":{"id":"` + account + `","cwd":"` + cwd + `"}}` + "\n"
+		if err := os.WriteFile(file, []byte(line), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		modTime := now.Add(-time.Hour)
+		if account == "home" {
+			modTime = now
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	pattern, err := ParsePathPattern("{account}/{year}-{month}-{day}")
+	if err != nil {
+		t.Fatalf("parse pattern: %v", err)
+	}
+	idx := NewIndex(base)
+	idx.SetPathPattern(pattern)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	date, _ := ParseDate("2026", "01", "09")
+	listed := idx.SessionsByDate(date)
+	if len(listed) != 2 || idx.FileCount() != 2 {
+		t.Fatalf("expected both files indexed, got %d listed, %d counted", len(listed), idx.FileCount())
+	}
+	for _, cwd := range []string{"/proj/work", "/proj/home"} {
+		files := idx.SessionsByCwd(cwd)
+		if len(files) != 1 || CwdForFile(files[0]) != cwd || !strings.Contains(files[0].Path, filepath.Join(strings.TrimPrefix(cwd, "/proj/"), "2026-01-09")) {
+			t.Fatalf("expected %s to resolve to its own file, got %+v", cwd, files)
+		}
+	}
+	file, ok := idx.Lookup(date, "same.jsonl")
+	if !ok || CwdForFile(file) != "/proj/home" || idx.Shadowed(file) {
+		t.Fatalf("expected lookup to resolve to the newest file, got %q (%s)", file.Path, CwdForFile(file))
+	}
+	if older := idx.SessionsByCwd("/proj/work")[0]; !idx.Shadowed(older) {
+		t.Fatalf("expected the older file to be reported as shadowed")
+	}
+	if got := idx.DuplicateNames(); len(got) != 1 || got[0] != "2026/01/09/same.jsonl" {
+		t.Fatalf("unexpected duplicate names: %v", got)
+	}
+}
+
+func TestParsePathPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"", "{year}/{month}", "{year}/{month}/{day}/{day}"} {
+		if _, err := ParsePathPattern(pattern); err == nil {
+			t.Fatalf("expected error for %q", pattern)
+		}
+	}
+}
+
+func TestIndexRefreshFollowSymlinks(t *testing.T) {
+	base := t.TempDir()
+	project := t.TempDir()
+	dayDir := filepath.Join(project, "09")
+	if err := os.MkdirAll(dayDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dayDir, "linked.jsonl"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// A link back to the project root creates a loop that must not recurse forever.
+	if err := os.Symlink(project, filepath.Join(dayDir, "loop")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(base, "2026"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Symlink(project, filepath.Join(base, "2026", "01")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	date, _ := ParseDate("2026", "01", "09")
+	idx := NewIndex(base)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, ok := idx.Lookup(date, "linked.jsonl"); ok {
+		t.Fatalf("expected symlinked directories to be ignored by default")
+	}
+
+	idx.SetFollowSymlinks(true)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh with symlinks: %v", err)
+	}
+	file, ok := idx.Lookup(date, "linked.jsonl")
+	if !ok {
+		t.Fatalf("expected symlinked session to be indexed")
+	}
+	if file.Path != filepath.Join(base, "2026", "01", "09", "linked.jsonl") {
+		t.Fatalf("expected logical path, got %s", file.Path)
+	}
+}
+
+func TestIndexRefreshIgnorePatterns(t *testing.T) {
+	base := t.TempDir()
+	for _, rel := range []string{"2026/01/09/keep.jsonl", "2026/01/09/fixture-a.jsonl", "2025/12/31/old.jsonl"} {
+		full := filepath.Join(base, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("{}\n"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	idx := NewIndex(base)
+	if err := idx.SetIgnorePatterns([]string{"fixture-*", "2025/*/*/*.jsonl"}); err != nil {
+		t.Fatalf("set ignore: %v", err)
+	}
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	date, _ := ParseDate("2026", "01", "09")
+	files := idx.SessionsByDate(date)
+	if len(files) != 1 || files[0].Name != "keep.jsonl" {
+		t.Fatalf("expected only keep.jsonl, got %+v", files)
+	}
+	if len(idx.Dates()) != 1 {
+		t.Fatalf("expected ignored 2025 date to be absent, got %v", idx.Dates())
+	}
+	if err := idx.SetIgnorePatterns([]string{"[bad"}); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+}
+
+func TestCompareCliVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"0.46.0", "0.46.0", 0},
+		{"0.9.0", "0.10.0", -1},
+		{"0.47.0", "0.47.0-alpha.1", 1},
+		{"v1.2", "1.2.0", 0},
+		{"0.1.2504301751", "0.1.2504161551", 1},
+	}
+	for _, tc := range cases {
+		if got := CompareCliVersions(tc.a, tc.b); got != tc.want {
+			t.Fatalf("CompareCliVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestIndexMarksEmptySessions(t *testing.T) {
+	base := t.TempDir()
+	dayDir := filepath.Join(base, "2026", "01", "09")
+	if err := os.MkdirAll(dayDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	meta := "{\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\",\"cwd\":\"/tmp\"}}\n"
+	files := map[string]string{
+		"empty.jsonl":   meta,
+		"context.jsonl": meta + "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"<environment_context>\\n  <cwd>/tmp</cwd>\\n</environment_context>\"}]}}\n",
+		"chat.jsonl":    meta + "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi\"}]}}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dayDir, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	idx := NewIndex(base)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	date, _ := ParseDate("2026", "01", "09")
+	for name, want := range map[string]bool{"empty.jsonl": true, "context.jsonl": true, "chat.jsonl": false} {
+		file, ok := idx.Lookup(date, name)
+		if !ok || file.Empty != want {
+			t.Fatalf("%s: expected Empty=%v, got %+v", name, want, file)
+		}
+	}
+}
+
+func TestIndexClassifiesSessions(t *testing.T) {
+	base := t.TempDir()
+	dayDir := filepath.Join(base, "2026", "01", "09")
+	if err := os.MkdirAll(dayDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	user := func(text string) string {
+		return fmt.Sprintf("{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":%q}]}}\n", text)
+	}
+	agent := "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Sure\"}]}}\n"
+	call := "{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{}\",\"call_id\":\"c1\"}}\n"
+	output := "{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"c1\",\"output\":\"ok\"}}\n"
+	abort := "{\"type\":\"event_msg\",\"payload\":{\"type\":\"turn_aborted\",\"reason\":\"interrupted\"}}\n"
+	files := map[string]struct {
+		data string
+		want SessionKind
+	}{
+		"coding.jsonl":  {user("Fix the bug") + call + output + call + output + agent, KindCoding},
+		"qa.jsonl":      {user("What is a goroutine?") + agent + user("And a channel?") + agent + call + output, KindQA},
+		"aborted.jsonl": {user("Refactor everything") + call + output + abort, KindAborted},
+		"resumed.jsonl": {user("Refactor everything") + abort + user("Just the parser") + agent, KindQA},
+		"empty.jsonl":   {"{\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\"}}\n", ""},
+	}
+	for name, file := range files {
+		if err := os.WriteFile(filepath.Join(dayDir, name), []byte(file.data), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	idx := NewIndex(base)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	date, _ := ParseDate("2026", "01", "09")
+	for name, file := range files {
+		got, ok := idx.Lookup(date, name)
+		if !ok || got.Kind != file.want {
+			t.Fatalf("%s: expected kind %q, got %q", name, file.want, got.Kind)
+		}
+	}
+}
+
+func TestIndexRefreshParallelKeepsOrder(t *testing.T) {
+	base := t.TempDir()
+	dayDir := filepath.Join(base, "2026", "01", "09")
+	if err := os.MkdirAll(dayDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	modTime := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	const count = 3 * refreshWorkers
+	for i := 0; i < count; i++ {
+		path := filepath.Join(dayDir, fmt.Sprintf("s%02d.jsonl", i))
+		data := fmt.Sprintf("{\"type\":\"session_meta\",\"payload\":{\"id\":\"id-%02d\",\"cwd\":\"/proj/%d\"}}\n", i, i%3)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		// Pairs share a modtime so the name tiebreak is exercised too.
+		stamp := modTime.Add(time.Duration(i/2) * time.Minute)
+		if err := os.Chtimes(path, stamp, stamp); err != nil {
+			t.Fatalf("chtimes: %v", err)
+		}
+	}
+
+	idx := NewIndex(base)
+	for round := 0; round < 2; round++ {
+		if err := idx.Refresh(); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+		date, _ := ParseDate("2026", "01", "09")
+		files := idx.SessionsByDate(date)
+		if len(files) != count {
+			t.Fatalf("expected %d sessions, got %d", count, len(files))
+		}
+		for i, file := range files {
+			// Newest pair first; within a pair the smaller name wins the tie.
+			n := count - 2 - 2*(i/2) + i%2
+			if want := fmt.Sprintf("s%02d.jsonl", n); file.Name != want {
+				t.Fatalf("round %d position %d: expected %s, got %s", round, i, want, file.Name)
+			}
+			if file.Meta == nil || file.Meta.ID != fmt.Sprintf("id-%02d", n) {
+				t.Fatalf("%s: expected its own meta, got %+v", file.Name, file.Meta)
+			}
+		}
+		if got := len(idx.SessionsByCwd("/proj/0")); got != count/3 {
+			t.Fatalf("expected %d sessions in /proj/0, got %d", count/3, got)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package sessions
+
+// SessionKind is a heuristic label for what a session was used for, derived
+// from the items in the head of its file.
+type SessionKind string
+
+const (
+	// KindCoding sessions are dominated by tool calls.
+	KindCoding SessionKind = "coding"
+	// KindQA sessions are mostly a message exchange.
+	KindQA SessionKind = "qa"
+	// KindAborted sessions had their only request interrupted.
+	KindAborted SessionKind = "aborted"
+)
+
+// SessionKinds lists every label in display order.
+var SessionKinds = []SessionKind{KindCoding, KindQA, KindAborted}
+
+// Label returns the text shown on badges and filter tabs.
+func (k SessionKind) Label() string {
+	switch k {
+	case KindCoding:
+		return "Coding"
+	case KindQA:
+		return "Q&A"
+	case KindAborted:
+		return "Aborted"
+	}
+	return ""
+}
+
+// ParseSessionKind maps a type= query value to a known kind.
+func ParseSessionKind(value string) (SessionKind, bool) {
+	for _, kind := range SessionKinds {
+		if string(kind) == value {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// kindHistogram tallies the items classify weighs. Tool outputs and
+// reasoning are ignored: every call has an output, and reasoning accompanies
+// both kinds of session alike.
+type kindHistogram struct {
+	toolCalls int
+	messages  int
+	userTurns int
+	aborted   bool
+}
+
+func (h *kindHistogram) add(item *RenderItem) {
+	if item.Aborted {
+		h.aborted = true
+	}
+	switch {
+	case item.ToolName != "":
+		h.toolCalls++
+	case item.Subtype == "message" && item.Role == "assistant":
+		h.messages++
+	case item.Subtype == "message" && item.Role == "user" && !IsAutoContextUserMessage(item.Content):
+		h.userTurns++
+		h.messages++
+	}
+}
+
+// classify applies the heuristic, in order:
+//   - aborted: a turn was interrupted, the session never got past its first
+//     request, and the whole file fit in the head window (complete is false
+//     when the line limit cut the read short);
+//   - coding: tool calls make up at least half of the tool calls plus
+//     messages;
+//   - qa: any other session with messages.
+//
+// Sessions with nothing to weigh stay unclassified ("").
+func (h kindHistogram) classify(complete bool) SessionKind {
+	switch {
+	case h.aborted && h.userTurns <= 1 && complete:
+		return KindAborted
+	case h.toolCalls > 0 && h.toolCalls >= h.messages:
+		return KindCoding
+	case h.messages > 0:
+		return KindQA
+	}
+	return ""
+}
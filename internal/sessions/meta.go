@@ -19,11 +19,19 @@ func ParseSessionMeta(path string) (*SessionMeta, error) {
 	reader := bufio.NewReader(file)
 	var meta *SessionMeta
 	var cwdCandidate string
+	firstLine := true
 
 	for {
 		line, err := reader.ReadBytes('\n')
+		if isPartialTrailingLine(line, err) {
+			break
+		}
 		if len(line) > 0 {
 			lineText := strings.TrimRight(string(line), "\r\n")
+			if firstLine {
+				lineText = strings.TrimPrefix(lineText, utf8BOM)
+				firstLine = false
+			}
 
 			if cwdCandidate == "" {
 				if content := extractMessageTextFromLine(lineText); content != "" {
@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Session represents a parsed conversation file.
@@ -14,6 +17,31 @@ type Session struct {
 	Path  string
 	Meta  *SessionMeta
 	Items []RenderItem
+	// InstructionsLine is the line that supplied Meta.Instructions, or 0 when there are none.
+	InstructionsLine int
+	// UnparsedLines counts non-empty lines that were not valid JSON.
+	UnparsedLines int
+	// TypeCounts counts JSON lines by envelope type and payload subtype, before
+	// anything is merged or omitted. Lines without a type are not counted.
+	TypeCounts map[EnvelopeType]int
+	// Cwds lists every working directory the session reported, in order of
+	// first appearance: Meta.Cwd first, then any others named by later
+	// environment_context (auto-context) messages after a cd.
+	Cwds []string
+}
+
+// EnvelopeType identifies a kind of JSONL line, e.g. response_item/function_call.
+// Subtype is empty for lines whose payload has no type.
+type EnvelopeType struct {
+	Type    string
+	Subtype string
+}
+
+func (s *Session) countType(envType, subtype string) {
+	if s.TypeCounts == nil {
+		s.TypeCounts = map[EnvelopeType]int{}
+	}
+	s.TypeCounts[EnvelopeType{Type: envType, Subtype: subtype}]++
 }
 
 // SessionMeta holds metadata from session_meta entries.
@@ -37,6 +65,25 @@ type RenderItem struct {
 	Content   string
 	Raw       string
 	Class     string
+	// CallID links a tool call to its output.
+	CallID string
+	// ToolName is the invoked tool's name, set on tool calls only.
+	ToolName string
+	// Output holds the paired tool output once fuseToolCalls has run.
+	Output     string
+	OutputLine int
+	// Hidden marks placeholder content, such as encrypted reasoning, that is
+	// shown in the transcript but kept out of the search index.
+	Hidden bool
+	// Aborted marks where a turn was interrupted: a turn_aborted event, or the
+	// <turn_aborted> block Codex injects into the next user message.
+	Aborted bool
+	// Unrecognized marks a generic item for a line whose type the parser does
+	// not know, kept only in lenient mode; such items are never merged.
+	Unrecognized bool
+	// Repeated counts the identical tool results DedupeToolOutputs collapsed
+	// into this one, itself included; 0 when it was not repeated.
+	Repeated int
 }
 
 type envelope struct {
@@ -56,13 +103,16 @@ type responseItemPayload struct {
 	Content   []responseContent `json:"content"`
 	Name      string            `json:"name"`
 	Arguments string            `json:"arguments"`
+	Input     string            `json:"input"`
+	Action    json.RawMessage   `json:"action"`
 	CallID    string            `json:"call_id"`
-	Output    string            `json:"output"`
+	Output    json.RawMessage   `json:"output"`
 }
 
 type eventMsgPayload struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	Reason  string `json:"reason"`
 }
 
 type directMessagePayload struct {
@@ -80,6 +130,9 @@ type metaLinePayload struct {
 	Instructions *string `json:"instructions"`
 }
 
+// utf8BOM is stripped from the first line; some editors prepend it on save.
+const utf8BOM = "\ufeff"
+
 // ParseSession reads a jsonl file and returns a parsed Session.
 func ParseSession(path string) (*Session, error) {
 	file, err := os.Open(path)
@@ -94,9 +147,15 @@ func ParseSession(path string) (*Session, error) {
 
 	for {
 		line, err := reader.ReadBytes('\n')
+		if isPartialTrailingLine(line, err) {
+			break
+		}
 		if len(line) > 0 {
 			lineNum++
 			lineText := strings.TrimRight(string(line), "\r\n")
+			if lineNum == 1 {
+				lineText = strings.TrimPrefix(lineText, utf8BOM)
+			}
 			item := parseLine(lineText, lineNum, session)
 			if item != nil {
 				session.Items = append(session.Items, *item)
@@ -111,43 +170,100 @@ func ParseSession(path string) (*Session, error) {
 		}
 	}
 
-	session.Items = mergeConsecutive(session.Items)
+	if session.Meta != nil && session.Meta.Cwd != "" && !slices.Contains(session.Cwds, session.Meta.Cwd) {
+		session.Cwds = append([]string{session.Meta.Cwd}, session.Cwds...)
+	}
+	if sortByTimeEnabled {
+		sortByTimestamp(session.Items)
+	}
+	if fuseToolCallsEnabled {
+		session.Items = fuseToolCalls(session.Items)
+	}
+	if mergeConsecutiveEnabled {
+		session.Items = mergeConsecutive(session.Items)
+	}
 
 	return session, nil
 }
 
+// isPartialTrailingLine reports whether line is a final, newline-less fragment
+// that is not valid JSON yet, as happens while Codex is still writing the file.
+func isPartialTrailingLine(line []byte, err error) bool {
+	if err != io.EOF || len(line) == 0 || bytes.HasSuffix(line, []byte("\n")) {
+		return false
+	}
+	return !json.Valid(bytes.TrimSpace(line))
+}
+
 func parseLine(lineText string, lineNum int, session *Session) *RenderItem {
 	var env envelope
 	if err := json.Unmarshal([]byte(lineText), &env); err != nil {
+		if strings.TrimSpace(lineText) != "" {
+			session.UnparsedLines++
+		}
 		return nil
 	}
 
+	if env.Type != "" && env.Type != "response_item" && env.Type != "event_msg" {
+		// Those two count their payload type once it is decoded.
+		session.countType(env.Type, "")
+	}
+
 	switch env.Type {
 	case "session_meta":
 		var meta SessionMeta
 		if err := json.Unmarshal(env.Payload, &meta); err == nil {
 			applyMeta(session, meta)
+			noteInstructionsLine(session, lineNum)
 		}
 		return nil
 	case "response_item":
 		return parseResponseItem(env, lineText, lineNum, session)
+	case "event_msg":
+		return parseEventMsg(env, lineText, lineNum, session)
 	case "message":
 		return parseDirectMessage(lineText, lineNum, session)
 	case "reasoning":
 		return parseDirectReasoning(lineText, lineNum)
 	default:
 		if env.Type == "" {
-			if applyMetaLine(session, lineText) {
+			ok := applyMetaLine(session, lineText)
+			noteInstructionsLine(session, lineNum)
+			if ok {
 				return nil
 			}
+		} else if lenientParseEnabled {
+			return unrecognizedItem(env, "", lineText, lineNum)
 		}
 		return nil
 	}
 }
 
+// unrecognizedItem renders a line of unknown type as its pretty-printed
+// payload (see SetLenientParseEnabled).
+func unrecognizedItem(env envelope, subtype, lineText string, lineNum int) *RenderItem {
+	content := "(empty)"
+	if payload := strings.TrimSpace(string(env.Payload)); payload != "" && payload != "null" {
+		content = codeFence(prettyJSON(payload), "json")
+	}
+	return &RenderItem{
+		Line:         lineNum,
+		Timestamp:    env.Timestamp,
+		Type:         env.Type,
+		Subtype:      subtype,
+		Title:        titleForType(env.Type, subtype, ""),
+		Content:      content,
+		Raw:          lineText,
+		Class:        roleClass(""),
+		Unrecognized: true,
+	}
+}
+
 func parseResponseItem(env envelope, lineText string, lineNum int, session *Session) *RenderItem {
 	var payload responseItemPayload
-	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+	err := json.Unmarshal(env.Payload, &payload)
+	session.countType(env.Type, payload.Type)
+	if err != nil {
 		return nil
 	}
 
@@ -157,7 +273,7 @@ func parseResponseItem(env envelope, lineText string, lineNum int, session *Sess
 		Type:      env.Type,
 		Subtype:   payload.Type,
 		Role:      payload.Role,
-		Title:     titleForType(env.Type, payload.Type),
+		Title:     titleForType(env.Type, payload.Type, ""),
 		Raw:       lineText,
 	}
 
@@ -173,8 +289,10 @@ func parseResponseItem(env envelope, lineText string, lineNum int, session *Sess
 		}
 		item.Content = extractContentText(payload.Content)
 		if payload.Role == "user" {
+			item.Aborted = hasTurnAbortedBlock(item.Content)
 			item.Content = trimUserRequest(item.Content)
 			maybeUpdateMetaCwd(session, item.Content)
+			recordContextCwd(session, item.Content)
 		}
 		if item.Content == "" {
 			item.Content = prettyJSON(string(env.Payload))
@@ -183,11 +301,24 @@ func parseResponseItem(env envelope, lineText string, lineNum int, session *Sess
 	case "reasoning":
 		item.Role = "assistant"
 		item.Class = roleClass("assistant")
-		item.Content = extractReasoningSummary(env.Payload)
-		if item.Content == "" {
-			item.Content = prettyJSON(string(env.Payload))
-		}
+		fillReasoning(&item, env.Payload)
+	case "function_call", "custom_tool_call", "local_shell_call", "web_search_call":
+		name := toolCallName(payload)
+		item.Role = "tool"
+		item.Class = roleClass("tool")
+		item.Title = titleForType(env.Type, payload.Type, name)
+		item.Content = formatToolArguments(toolCallArguments(payload))
+		item.CallID = payload.CallID
+		item.ToolName = name
+	case "function_call_output", "custom_tool_call_output":
+		item.Role = "tool"
+		item.Class = roleClass("tool")
+		item.Content = formatToolOutput(toolOutputText(payload.Output))
+		item.CallID = payload.CallID
 	default:
+		if lenientParseEnabled {
+			return unrecognizedItem(env, payload.Type, lineText, lineNum)
+		}
 		return nil
 	}
 
@@ -217,6 +348,7 @@ func parseDirectMessage(lineText string, lineNum int, session *Session) *RenderI
 	if payload.Role == "user" {
 		item.Content = trimUserRequest(item.Content)
 		maybeUpdateMetaCwd(session, item.Content)
+		recordContextCwd(session, item.Content)
 	}
 	if item.Content == "" {
 		item.Content = prettyJSON(lineText)
@@ -226,52 +358,72 @@ func parseDirectMessage(lineText string, lineNum int, session *Session) *RenderI
 }
 
 func parseDirectReasoning(lineText string, lineNum int) *RenderItem {
-	content := extractReasoningSummary(json.RawMessage(lineText))
-	if content == "" {
-		content = prettyJSON(lineText)
-	}
-	return &RenderItem{
+	item := &RenderItem{
 		Line:    lineNum,
 		Type:    "response_item",
 		Subtype: "reasoning",
 		Role:    "assistant",
 		Title:   "Reasoning",
-		Content: content,
 		Class:   roleClass("assistant"),
 	}
+	fillReasoning(item, json.RawMessage(lineText))
+	return item
 }
 
-func parseEventMsg(env envelope, lineText string, lineNum int) *RenderItem {
-	var payload eventMsgPayload
-	if err := json.Unmarshal(env.Payload, &payload); err != nil {
-		return &RenderItem{
-			Line:      lineNum,
-			Timestamp: env.Timestamp,
-			Type:      env.Type,
-			Title:     "event_msg",
-			Content:   prettyJSON(lineText),
-			Raw:       lineText,
-			Class:     roleClass("system"),
-		}
-	}
+// ReasoningHidden replaces reasoning that only carries encrypted_content,
+// which is base64 noise to a reader.
+const ReasoningHidden = "(reasoning hidden)"
 
-	content := payload.Message
-	if content == "" {
-		content = prettyJSON(string(env.Payload))
+// fillReasoning sets a reasoning item's content: the summary, else the
+// plaintext content (reasoning_text, as open models emit), else a hidden
+// placeholder when the payload is encrypted, else the raw payload.
+func fillReasoning(item *RenderItem, raw json.RawMessage) {
+	text, encrypted := extractReasoningText(raw)
+	switch {
+	case text != "":
+		item.Content = text
+	case encrypted:
+		item.Content = ReasoningHidden
+		item.Hidden = true
+	default:
+		item.Content = prettyJSON(string(raw))
 	}
+}
 
+// parseEventMsg renders a turn_aborted event_msg; other events stay omitted
+// since the response_items around them already carry the conversation.
+func parseEventMsg(env envelope, lineText string, lineNum int, session *Session) *RenderItem {
+	var payload eventMsgPayload
+	err := json.Unmarshal(env.Payload, &payload)
+	session.countType(env.Type, payload.Type)
+	if err != nil || payload.Type != "turn_aborted" {
+		return nil
+	}
+	content := "Turn aborted"
+	if reason := strings.TrimSpace(payload.Reason); reason != "" {
+		content += ": " + reason
+	}
 	return &RenderItem{
 		Line:      lineNum,
 		Timestamp: env.Timestamp,
 		Type:      env.Type,
 		Subtype:   payload.Type,
-		Title:     titleForType(env.Type, payload.Type),
+		Role:      "system",
+		Title:     titleForType(env.Type, payload.Type, ""),
 		Content:   content,
 		Raw:       lineText,
-		Class:     roleClass("user"),
+		Class:     roleClass("system"),
+		Aborted:   true,
 	}
 }
 
+// hasTurnAbortedBlock reports whether content carries a complete
+// <turn_aborted>…</turn_aborted> marker.
+func hasTurnAbortedBlock(content string) bool {
+	open := strings.Index(content, "<turn_aborted>")
+	return open != -1 && strings.Contains(content[open:], "</turn_aborted>")
+}
+
 func extractContentText(contents []responseContent) string {
 	if len(contents) == 0 {
 		return ""
@@ -286,27 +438,33 @@ func extractContentText(contents []responseContent) string {
 	return strings.TrimSpace(strings.Join(parts, "\n"))
 }
 
-func extractReasoningSummary(raw json.RawMessage) string {
+// extractReasoningText returns a reasoning payload's summary text, falling
+// back to its plaintext content, and whether it carries encrypted_content.
+func extractReasoningText(raw json.RawMessage) (string, bool) {
 	var payload struct {
-		Summary []struct {
-			Type string `json:"type"`
-			Text string `json:"text"`
-		} `json:"summary"`
+		Summary          []responseContent `json:"summary"`
+		Content          []responseContent `json:"content"`
+		EncryptedContent string            `json:"encrypted_content"`
 	}
 	if err := json.Unmarshal(raw, &payload); err != nil {
-		return ""
+		return "", false
 	}
-	if len(payload.Summary) == 0 {
-		return ""
+	encrypted := payload.EncryptedContent != ""
+	if text := joinContentText(payload.Summary); text != "" {
+		return text, encrypted
 	}
-	parts := make([]string, 0, len(payload.Summary))
-	for _, item := range payload.Summary {
-		if item.Text == "" {
+	return joinContentText(payload.Content), encrypted
+}
+
+func joinContentText(parts []responseContent) string {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Text == "" {
 			continue
 		}
-		parts = append(parts, item.Text)
+		texts = append(texts, part.Text)
 	}
-	return strings.TrimSpace(strings.Join(parts, "\n"))
+	return strings.TrimSpace(strings.Join(texts, "\n"))
 }
 
 func applyMeta(session *Session, meta SessionMeta) {
@@ -343,6 +501,12 @@ func mergeMeta(target *SessionMeta, meta SessionMeta) {
 	}
 }
 
+func noteInstructionsLine(session *Session, lineNum int) {
+	if session.InstructionsLine == 0 && session.Meta != nil && strings.TrimSpace(session.Meta.Instructions) != "" {
+		session.InstructionsLine = lineNum
+	}
+}
+
 func applyMetaLine(session *Session, lineText string) bool {
 	var meta metaLinePayload
 	if err := json.Unmarshal([]byte(lineText), &meta); err != nil {
@@ -373,14 +537,19 @@ func prettyJSON(raw string) string {
 	return buf.String()
 }
 
-func titleForType(eventType, subType string) string {
+// titleForType returns the item heading; name, when set, identifies the tool
+// for tool call subtypes (e.g. "Tool call: web_search").
+func titleForType(eventType, subType, name string) string {
 	if eventType == "response_item" {
 		switch subType {
 		case "message":
 			return "Message"
-		case "function_call":
+		case "function_call", "custom_tool_call", "local_shell_call", "web_search_call":
+			if name != "" {
+				return "Tool call: " + name
+			}
 			return "Tool call"
-		case "function_call_output":
+		case "function_call_output", "custom_tool_call_output":
 			return "Tool output"
 		case "reasoning":
 			return "Reasoning"
@@ -389,14 +558,99 @@ func titleForType(eventType, subType string) string {
 		}
 	}
 	if eventType == "event_msg" {
-		if subType == "user_message" {
+		switch subType {
+		case "user_message":
 			return "User context"
+		case "turn_aborted":
+			return "Turn aborted"
 		}
 		return "Event"
 	}
 	return strings.ReplaceAll(eventType, "_", " ")
 }
 
+// toolCallName returns the display name for a tool invocation payload.
+func toolCallName(payload responseItemPayload) string {
+	switch payload.Type {
+	case "local_shell_call":
+		return "shell"
+	case "web_search_call":
+		return "web_search"
+	}
+	if payload.Name != "" {
+		return payload.Name
+	}
+	return "tool"
+}
+
+// toolCallArguments returns the raw argument text for any tool call shape.
+func toolCallArguments(payload responseItemPayload) string {
+	switch {
+	case payload.Arguments != "":
+		return payload.Arguments
+	case payload.Input != "":
+		return payload.Input
+	case len(payload.Action) > 0:
+		return string(payload.Action)
+	}
+	return ""
+}
+
+// formatToolArguments wraps tool arguments in a code fence, pretty-printing JSON.
+func formatToolArguments(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "{}" {
+		return ""
+	}
+	if json.Valid([]byte(raw)) {
+		return codeFence(prettyJSON(raw), "json")
+	}
+	return codeFence(raw, "")
+}
+
+// toolOutputText unwraps a tool output that is usually a JSON string but may be
+// an inline JSON value.
+func toolOutputText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text
+	}
+	return string(raw)
+}
+
+// formatToolOutput renders JSON outputs as a pretty-printed json fence and
+// anything else as a plain-text fence.
+func formatToolOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return ""
+	}
+	if (strings.HasPrefix(output, "{") || strings.HasPrefix(output, "[")) && json.Valid([]byte(output)) {
+		return codeFence(prettyJSON(output), "json")
+	}
+	return codeFence(output, "")
+}
+
+// codeFence wraps body in a markdown fence longer than any backtick run inside it.
+func codeFence(body, lang string) string {
+	longest, run := 0, 0
+	for _, ch := range body {
+		if ch == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+			continue
+		}
+		run = 0
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + body + "\n" + fence
+}
+
 func titleForRole(role string) string {
 	switch strings.ToLower(role) {
 	case "user":
@@ -433,18 +687,25 @@ func mergeConsecutive(items []RenderItem) []RenderItem {
 	current := items[0]
 	for i := 1; i < len(items); i++ {
 		item := items[i]
-		if current.Type == item.Type && current.Subtype == item.Subtype && current.Role == item.Role {
-			if isUserMessage(item) {
+		if current.Type == item.Type && current.Subtype == item.Subtype && current.Role == item.Role && !isToolItem(item) && !item.Unrecognized {
+			// An abort marker survives the merge so the turn stays labelled.
+			aborted := current.Aborted || item.Aborted
+			switch {
+			case isUserMessage(item):
 				current = item
-				continue
-			}
-			if strings.TrimSpace(item.Content) != "" {
+			case item.Hidden:
+				// A placeholder adds nothing next to real content...
+			case current.Hidden:
+				// ...and is replaced by it.
+				current = item
+			case strings.TrimSpace(item.Content) != "":
 				if strings.TrimSpace(current.Content) != "" {
 					current.Content = current.Content + "\n\n" + item.Content
 				} else {
 					current.Content = item.Content
 				}
 			}
+			current.Aborted = aborted
 			continue
 		}
 		out = append(out, current)
@@ -454,6 +715,121 @@ func mergeConsecutive(items []RenderItem) []RenderItem {
 	return out
 }
 
+// sortByTimestamp orders items by their parsed timestamps, which may carry any
+// UTC offset. The sort is stable, and an item whose timestamp is missing or
+// unparseable keeps the time of the item before it so it stays in place.
+func sortByTimestamp(items []RenderItem) {
+	keys := make([]time.Time, len(items))
+	var last time.Time
+	for i, item := range items {
+		if parsed, err := time.Parse(time.RFC3339Nano, item.Timestamp); err == nil {
+			last = parsed
+		}
+		keys[i] = last
+	}
+	sort.Stable(itemsByTime{items: items, keys: keys})
+}
+
+type itemsByTime struct {
+	items []RenderItem
+	keys  []time.Time
+}
+
+func (s itemsByTime) Len() int           { return len(s.items) }
+func (s itemsByTime) Less(i, j int) bool { return s.keys[i].Before(s.keys[j]) }
+func (s itemsByTime) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// fuseToolCalls folds each tool output into the call with the same CallID.
+// Outputs without a preceding call, and calls that never got an output, are
+// left as standalone items.
+func fuseToolCalls(items []RenderItem) []RenderItem {
+	out := make([]RenderItem, 0, len(items))
+	pending := map[string]int{}
+	for _, item := range items {
+		if isToolOutput(item) && item.CallID != "" {
+			if index, ok := pending[item.CallID]; ok {
+				out[index].Output = item.Content
+				out[index].OutputLine = item.Line
+				delete(pending, item.CallID)
+				continue
+			}
+		}
+		if isToolItem(item) && !isToolOutput(item) && item.CallID != "" {
+			pending[item.CallID] = len(out)
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// DedupeToolOutputs collapses runs of identical tool results, as polling
+// loops produce, into the first one with Repeated set to the run length. A
+// result is a standalone output, a call fused with its output, or a call
+// directly followed by the output with its CallID; results are identical
+// when their tool, arguments, and output match after trimming.
+//
+// ParseSession keeps every item, so Markdown exports and search still see
+// each output; only the session page calls this. It returns a new slice and
+// leaves items as they are when merging is disabled (-no-merge).
+func DedupeToolOutputs(items []RenderItem) []RenderItem {
+	if !mergeConsecutiveEnabled {
+		return items
+	}
+	out := make([]RenderItem, 0, len(items))
+	var last toolResultKey
+	// lastAt is the item in out carrying the previous result's output, or -1
+	// when the previous item was not a result.
+	lastAt := -1
+	for i := 0; i < len(items); {
+		key, size, ok := toolResultAt(items, i)
+		if !ok {
+			out = append(out, items[i])
+			lastAt = -1
+			i++
+			continue
+		}
+		if lastAt >= 0 && key == last {
+			if out[lastAt].Repeated == 0 {
+				out[lastAt].Repeated = 1
+			}
+			out[lastAt].Repeated++
+		} else {
+			out = append(out, items[i:i+size]...)
+			last, lastAt = key, len(out)-1
+		}
+		i += size
+	}
+	return out
+}
+
+type toolResultKey struct {
+	title, arguments, output string
+}
+
+// toolResultAt reports the tool result starting at items[i] and how many
+// items it spans.
+func toolResultAt(items []RenderItem, i int) (toolResultKey, int, bool) {
+	item := items[i]
+	switch {
+	case isToolOutput(item):
+		return toolResultKey{output: strings.TrimSpace(item.Content)}, 1, true
+	case item.ToolName == "":
+		return toolResultKey{}, 0, false
+	case item.Output != "":
+		return toolResultKey{item.Title, strings.TrimSpace(item.Content), strings.TrimSpace(item.Output)}, 1, true
+	case item.CallID != "" && i+1 < len(items) && isToolOutput(items[i+1]) && items[i+1].CallID == item.CallID:
+		return toolResultKey{item.Title, strings.TrimSpace(item.Content), strings.TrimSpace(items[i+1].Content)}, 2, true
+	}
+	return toolResultKey{}, 0, false
+}
+
+func isToolOutput(item RenderItem) bool {
+	return item.Subtype == "function_call_output" || item.Subtype == "custom_tool_call_output"
+}
+
 func trimUserRequest(content string) string {
 	if !trimUserRequestEnabled {
 		return content
@@ -461,13 +837,29 @@ func trimUserRequest(content string) string {
 	if IsAutoContextUserMessage(content) {
 		return content
 	}
-	marker := "## My request for Codex:"
-	index := strings.Index(content, marker)
-	if index == -1 {
+	// cut is where the kept text starts: after the earliest marker, or after
+	// the latest one when trimming from the last occurrence.
+	cut := -1
+	for _, marker := range trimUserRequestMarkers {
+		if marker == "" {
+			continue
+		}
+		index := strings.Index(content, marker)
+		if trimFromLastMarker {
+			index = strings.LastIndex(content, marker)
+		}
+		if index == -1 {
+			continue
+		}
+		end := index + len(marker)
+		if cut == -1 || (trimFromLastMarker && end > cut) || (!trimFromLastMarker && end < cut) {
+			cut = end
+		}
+	}
+	if cut == -1 {
 		return content
 	}
-	trimmed := content[index+len(marker):]
-	return strings.TrimSpace(trimmed)
+	return strings.TrimSpace(content[cut:])
 }
 
 // IsAutoContextUserMessage reports whether the content looks like auto-injected context.
@@ -497,12 +889,25 @@ func maybeUpdateMetaCwd(session *Session, content string) {
 	}
 }
 
+// recordContextCwd adds the cwd of an auto-context message to session.Cwds.
+func recordContextCwd(session *Session, content string) {
+	if session == nil || !IsAutoContextUserMessage(content) {
+		return
+	}
+	if cwd := extractCwdFromText(content); cwd != "" && !slices.Contains(session.Cwds, cwd) {
+		session.Cwds = append(session.Cwds, cwd)
+	}
+}
+
 func extractCwdFromText(content string) string {
 	for _, line := range strings.Split(content, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
+		if strings.HasPrefix(line, "<cwd>") && strings.HasSuffix(line, "</cwd>") {
+			return strings.TrimSpace(line[len("<cwd>") : len(line)-len("</cwd>")])
+		}
 		if strings.HasPrefix(line, "Current working directory:") {
 			return strings.TrimSpace(strings.TrimPrefix(line, "Current working directory:"))
 		}
@@ -513,17 +918,74 @@ func extractCwdFromText(content string) string {
 	return ""
 }
 
+// DefaultTrimMarker is the heading Codex prompts put before the actual request.
+const DefaultTrimMarker = "## My request for Codex:"
+
 var trimUserRequestEnabled = true
 
+var trimUserRequestMarkers = []string{DefaultTrimMarker}
+
+var trimFromLastMarker = false
+
+var mergeConsecutiveEnabled = true
+
+var fuseToolCallsEnabled = false
+
+var sortByTimeEnabled = false
+
+var lenientParseEnabled = false
+
+// SetLenientParseEnabled controls whether ParseSession keeps response_item
+// subtypes and envelope types it does not recognize as generic items showing
+// their payload. The default drops them.
+func SetLenientParseEnabled(enabled bool) {
+	lenientParseEnabled = enabled
+}
+
+// SetSortByTimeEnabled controls whether ParseSession reorders items by timestamp
+// before fusing and merging them. The default keeps file order.
+func SetSortByTimeEnabled(enabled bool) {
+	sortByTimeEnabled = enabled
+}
+
+// SetFuseToolCallsEnabled controls whether ParseSession folds tool outputs into
+// their calls (matched by call_id) instead of rendering them as separate items.
+func SetFuseToolCallsEnabled(enabled bool) {
+	fuseToolCallsEnabled = enabled
+}
+
+// SetMergeConsecutiveEnabled controls whether ParseSession merges adjacent same-type items.
+// Disabling it keeps every message in file order, which is useful when debugging transcripts.
+func SetMergeConsecutiveEnabled(enabled bool) {
+	mergeConsecutiveEnabled = enabled
+}
+
 // SetTrimUserRequestEnabled controls whether user messages are trimmed to the request marker.
 func SetTrimUserRequestEnabled(enabled bool) {
 	trimUserRequestEnabled = enabled
 }
 
+// SetTrimUserRequestMarkers sets the markers user messages are trimmed to.
+// Whichever marker appears is used; with none, marker trimming is off.
+func SetTrimUserRequestMarkers(markers []string) {
+	trimUserRequestMarkers = append([]string(nil), markers...)
+}
+
+// SetTrimFromLastMarker controls whether trimming keeps the text after the
+// last marker occurrence (for nested requests) instead of the first.
+func SetTrimFromLastMarker(enabled bool) {
+	trimFromLastMarker = enabled
+}
+
 func isUserMessage(item RenderItem) bool {
 	return item.Subtype == "message" && item.Role == "user"
 }
 
+// isToolItem reports whether item is a tool call or output; each stays separate.
+func isToolItem(item RenderItem) bool {
+	return item.Role == "tool"
+}
+
 func isAgentsInstructionsOnly(text string) bool {
 	if !strings.HasPrefix(text, "# AGENTS.md instructions") {
 		return false
@@ -3,6 +3,7 @@ package sessions
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -32,17 +33,80 @@ func TestParseSession(t *testing.T) {
 	if session.Meta == nil || session.Meta.ID != "abc" {
 		t.Fatalf("expected session meta")
 	}
-	if len(session.Items) != 3 {
-		t.Fatalf("expected 3 items, got %d", len(session.Items))
+	if session.UnparsedLines != 1 {
+		t.Fatalf("expected 1 unparsed line, got %d", session.UnparsedLines)
+	}
+	if session.InstructionsLine != 1 {
+		t.Fatalf("expected instructions on line 1, got %d", session.InstructionsLine)
+	}
+	if len(session.Items) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(session.Items))
 	}
 	if session.Items[0].Content != "Only this" {
 		t.Fatalf("unexpected message content: %q", session.Items[0].Content)
 	}
-	if session.Items[1].Content != "Reason" {
-		t.Fatalf("expected reasoning summary, got %q", session.Items[1].Content)
+	if session.Items[1].Title != "Tool call: shell_command" || session.Items[1].Role != "tool" {
+		t.Fatalf("unexpected tool call item: %#v", session.Items[1])
+	}
+	if session.Items[2].Title != "Tool output" || session.Items[2].Content != "```\ndone\n```" {
+		t.Fatalf("unexpected tool output item: %#v", session.Items[2])
+	}
+	if session.Items[3].Content != "Reason" {
+		t.Fatalf("expected reasoning summary, got %q", session.Items[3].Content)
+	}
+	if session.Items[4].Content != "Later" {
+		t.Fatalf("expected last user message, got %q", session.Items[4].Content)
+	}
+}
+
+func TestFormatToolOutput(t *testing.T) {
+	if got := formatToolOutput(`{"ok":true,"items":[1]}`); !strings.HasPrefix(got, "```json\n{\n  \"ok\": true") {
+		t.Fatalf("expected pretty JSON fence, got %q", got)
+	}
+	if got := formatToolOutput("exit 0\n# not a heading"); got != "```\nexit 0\n# not a heading\n```" {
+		t.Fatalf("expected plain fence, got %q", got)
+	}
+	if got := formatToolOutput("has ``` inside"); !strings.HasPrefix(got, "````\n") {
+		t.Fatalf("expected longer fence, got %q", got)
+	}
+	if got := toolOutputText([]byte(`{"output":"x"}`)); got != `{"output":"x"}` {
+		t.Fatalf("expected inline JSON output preserved, got %q", got)
+	}
+}
+
+func TestParseSessionToolCalls(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"read_file\",\"arguments\":\"{\\\"path\\\":\\\"a.go\\\"}\",\"call_id\":\"c1\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"web_search_call\",\"action\":{\"type\":\"search\",\"query\":\"golang\"}}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"custom_tool_call\",\"name\":\"apply_patch\",\"input\":\"*** Begin Patch\",\"call_id\":\"c2\"}}\n"
+
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 3 {
+		t.Fatalf("expected 3 unmerged tool items, got %d", len(session.Items))
+	}
+	wantTitles := []string{"Tool call: read_file", "Tool call: web_search", "Tool call: apply_patch"}
+	for i, want := range wantTitles {
+		if session.Items[i].Title != want {
+			t.Fatalf("item %d: got title %q want %q", i, session.Items[i].Title, want)
+		}
+	}
+	if !strings.Contains(session.Items[0].Content, "```json") || !strings.Contains(session.Items[0].Content, "\"path\": \"a.go\"") {
+		t.Fatalf("expected pretty JSON arguments, got %q", session.Items[0].Content)
+	}
+	if !strings.Contains(session.Items[1].Content, "golang") {
+		t.Fatalf("expected web search query, got %q", session.Items[1].Content)
 	}
-	if session.Items[2].Content != "Later" {
-		t.Fatalf("expected last user message, got %q", session.Items[2].Content)
+	if !strings.Contains(session.Items[2].Content, "*** Begin Patch") {
+		t.Fatalf("expected custom tool input, got %q", session.Items[2].Content)
 	}
 }
 
@@ -80,3 +144,501 @@ func TestParseSessionDirectFormat(t *testing.T) {
 		t.Fatalf("unexpected reasoning content: %q", session.Items[2].Content)
 	}
 }
+
+func TestParseSessionBOMAndCRLF(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "\ufeff" +
+		"{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"session_meta\",\"payload\":{\"id\":\"bom\",\"cwd\":\"/tmp\"}}\r\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hi\"}]}}\r\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Last\"}]}}"
+
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if session.Meta == nil || session.Meta.ID != "bom" {
+		t.Fatalf("expected meta id from BOM-prefixed line, got %#v", session.Meta)
+	}
+	if len(session.Items) != 2 || session.Items[1].Content != "Last" {
+		t.Fatalf("expected final CRLF-file line to parse, got %#v", session.Items)
+	}
+
+	meta, err := ParseSessionMeta(filePath)
+	if err != nil {
+		t.Fatalf("parse meta: %v", err)
+	}
+	if meta == nil || meta.ID != "bom" || meta.Cwd != "/tmp" {
+		t.Fatalf("unexpected meta: %#v", meta)
+	}
+}
+
+func TestParseSessionPartialTrailingLine(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"session_meta\",\"payload\":{\"id\":\"live\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hi\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Current working directory: /tm"
+
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 1 || session.Items[0].Content != "Hi" {
+		t.Fatalf("expected prior items preserved, got %#v", session.Items)
+	}
+
+	meta, err := ParseSessionMeta(filePath)
+	if err != nil {
+		t.Fatalf("parse meta: %v", err)
+	}
+	if meta == nil || meta.ID != "live" || meta.Cwd != "" {
+		t.Fatalf("unexpected meta: %#v", meta)
+	}
+}
+
+func TestParseSessionNoMerge(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Earlier\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Later\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"One\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Two\"}]}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	SetMergeConsecutiveEnabled(false)
+	defer SetMergeConsecutiveEnabled(true)
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 4 {
+		t.Fatalf("expected 4 unmerged items, got %d", len(session.Items))
+	}
+	want := []string{"Earlier", "Later", "One", "Two"}
+	for i, item := range session.Items {
+		if item.Content != want[i] {
+			t.Fatalf("item %d: got %q want %q", i, item.Content, want[i])
+		}
+	}
+}
+
+func TestParseSessionFuseToolCalls(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"orphan\",\"output\":\"lonely\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{\\\"command\\\":[\\\"ls\\\"]}\",\"call_id\":\"call_1\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{}\",\"call_id\":\"call_2\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"call_1\",\"output\":\"file.txt\"}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	SetFuseToolCallsEnabled(true)
+	defer SetFuseToolCallsEnabled(false)
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 3 {
+		t.Fatalf("expected orphan output, fused call, and orphan call; got %d items", len(session.Items))
+	}
+	if session.Items[0].Title != "Tool output" || session.Items[0].Output != "" {
+		t.Fatalf("expected standalone orphan output, got %+v", session.Items[0])
+	}
+	fused := session.Items[1]
+	if fused.CallID != "call_1" || fused.Output != "```\nfile.txt\n```" || fused.OutputLine != 4 {
+		t.Fatalf("expected call_1 fused with its output, got %+v", fused)
+	}
+	if session.Items[2].CallID != "call_2" || session.Items[2].Output != "" {
+		t.Fatalf("expected call without output to stay as-is, got %+v", session.Items[2])
+	}
+}
+
+func TestDedupeToolOutputs(t *testing.T) {
+	call := func(id, command string) string {
+		return "{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{\\\"command\\\":[\\\"" + command + "\\\"]}\",\"call_id\":\"" + id + "\"}}\n"
+	}
+	output := func(id, text string) string {
+		return "{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"" + id + "\",\"output\":\"" + text + "\"}}\n"
+	}
+	data := call("c1", "status") + output("c1", "pending") +
+		call("c2", "status") + output("c2", "pending ") +
+		call("c3", "status") + output("c3", "pending") +
+		call("c4", "status") + output("c4", "done") +
+		output("o1", "same") + output("o2", "same")
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 10 {
+		t.Fatalf("expected ParseSession to keep every output, got %d items", len(session.Items))
+	}
+	items := DedupeToolOutputs(session.Items)
+	if len(items) != 5 {
+		t.Fatalf("expected the repeated poll and output collapsed, got %d items", len(items))
+	}
+	if first := items[1]; first.CallID != "c1" || first.Repeated != 3 {
+		t.Fatalf("expected the first poll output repeated 3 times, got %+v", first)
+	}
+	if done := items[3]; done.CallID != "c4" || done.Repeated != 0 {
+		t.Fatalf("expected the differing output kept separate, got %+v", done)
+	}
+	if same := items[4]; same.CallID != "o1" || same.Repeated != 2 {
+		t.Fatalf("expected consecutive standalone outputs collapsed, got %+v", same)
+	}
+	if session.Items[1].Repeated != 0 {
+		t.Fatalf("expected the parsed items left untouched")
+	}
+
+	SetFuseToolCallsEnabled(true)
+	session, err = ParseSession(filePath)
+	SetFuseToolCallsEnabled(false)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if items := DedupeToolOutputs(session.Items); len(items) != 3 || items[0].Repeated != 3 || items[1].Output == "" {
+		t.Fatalf("expected fused calls deduplicated too, got %+v", items)
+	}
+
+	SetMergeConsecutiveEnabled(false)
+	defer SetMergeConsecutiveEnabled(true)
+	session, err = ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if items := DedupeToolOutputs(session.Items); len(items) != 10 {
+		t.Fatalf("expected -no-merge to keep every output, got %d items", len(items))
+	}
+}
+
+func TestParseSessionLenient(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hello\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"ghost_snapshot\",\"commit\":\"abc\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"turn_context\",\"payload\":{\"model\":\"m1\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"turn_context\",\"payload\":{\"model\":\"m2\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\"}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 1 {
+		t.Fatalf("expected unknown lines dropped by default, got %d items", len(session.Items))
+	}
+
+	SetLenientParseEnabled(true)
+	defer SetLenientParseEnabled(false)
+	session, err = ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 4 {
+		t.Fatalf("expected the message plus three unrecognized items, got %d: %+v", len(session.Items), session.Items)
+	}
+	snapshot := session.Items[1]
+	if !snapshot.Unrecognized || snapshot.Subtype != "ghost_snapshot" || snapshot.Title != "Response item" || !strings.Contains(snapshot.Content, "\"commit\": \"abc\"") {
+		t.Fatalf("unexpected generic response item: %+v", snapshot)
+	}
+	if context := session.Items[3]; !context.Unrecognized || context.Title != "turn context" || context.Line != 4 || !strings.Contains(context.Content, "m2") || strings.Contains(context.Content, "m1") {
+		t.Fatalf("expected unmerged envelope items, got %+v", context)
+	}
+}
+
+func TestParseSessionCwds(t *testing.T) {
+	context := func(cwd string) string {
+		return "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"<environment_context>\\n  <cwd>" + cwd + "</cwd>\\n  <shell>zsh</shell>\\n</environment_context>\"}]}}\n"
+	}
+	lines := "{\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\",\"cwd\":\"/repo\"}}\n" +
+		context("/repo") +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"CWD: /typed/by/user\\nplease cd into web\"}]}}\n" +
+		context("/repo/web") +
+		context("/repo")
+	filePath := filepath.Join(t.TempDir(), "session.jsonl")
+	if err := os.WriteFile(filePath, []byte(lines), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("ParseSession: %v", err)
+	}
+	if got := strings.Join(session.Cwds, ","); got != "/repo,/repo/web" {
+		t.Fatalf("unexpected cwds: %q", got)
+	}
+}
+
+func TestParseSessionSummary(t *testing.T) {
+	long := strings.Repeat("word ", 30)
+	cases := []struct {
+		name  string
+		lines string
+		want  string
+	}{
+		{
+			name: "skips auto context and trims marker",
+			lines: "{\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\",\"cwd\":\"/tmp\"}}\n" +
+				"{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"<environment_context>\\nCurrent working directory: /tmp\\n</environment_context>\"}]}}\n" +
+				"{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Files\\n\\n## My request for Codex:\\nFix the\\nflaky test\"}]}}\n",
+			want: "Fix the flaky test",
+		},
+		{
+			name:  "truncates long messages",
+			lines: "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"" + long + "\"}]}}\n",
+			want:  strings.TrimSpace(long[:79]) + "…",
+		},
+		{
+			name:  "no user message",
+			lines: "{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi\"}]}}\n",
+			want:  "",
+		},
+	}
+	for _, tc := range cases {
+		filePath := filepath.Join(t.TempDir(), "session.jsonl")
+		if err := os.WriteFile(filePath, []byte(tc.lines), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		got, err := ParseSessionSummary(filePath)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: got %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseSessionSortByTime(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:06Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Second\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T03:00:05+02:00\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"First\"}]}}\n" +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Untimed\"}]}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	SetMergeConsecutiveEnabled(false)
+	defer SetMergeConsecutiveEnabled(true)
+
+	contents := func() []string {
+		session, err := ParseSession(filePath)
+		if err != nil {
+			t.Fatalf("parse: %v", err)
+		}
+		var out []string
+		for _, item := range session.Items {
+			out = append(out, item.Content)
+		}
+		return out
+	}
+	if got := strings.Join(contents(), ","); got != "Second,First,Untimed" {
+		t.Fatalf("expected file order by default, got %s", got)
+	}
+
+	SetSortByTimeEnabled(true)
+	defer SetSortByTimeEnabled(false)
+	if got := strings.Join(contents(), ","); got != "First,Untimed,Second" {
+		t.Fatalf("expected timestamp order, got %s", got)
+	}
+}
+
+func TestParseSessionEncryptedReasoning(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"reasoning\",\"summary\":[],\"encrypted_content\":\"gAAAAABoZ3JhbmRvbWJhc2U2NA==\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Done\"}]}}\n" +
+		"{\"type\":\"reasoning\",\"id\":\"rs_1\",\"encrypted_content\":\"gAAAAABvdGhlcg==\"}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"reasoning\",\"summary\":[{\"type\":\"summary_text\",\"text\":\"Plain\"}]}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 3 {
+		t.Fatalf("expected placeholder, message, and merged reasoning; got %d items", len(session.Items))
+	}
+	first := session.Items[0]
+	if !first.Hidden || first.Content != ReasoningHidden || first.Subtype != "reasoning" {
+		t.Fatalf("expected a hidden reasoning placeholder, got %+v", first)
+	}
+	// The direct-format placeholder merges into the following summary.
+	if last := session.Items[2]; last.Hidden || last.Content != "Plain" {
+		t.Fatalf("expected the plaintext summary to replace the placeholder, got %+v", last)
+	}
+	for _, item := range session.Items {
+		if strings.Contains(item.Content, "gAAAA") {
+			t.Fatalf("encrypted content leaked into item %+v", item)
+		}
+	}
+}
+
+func TestParseSessionPlaintextReasoningContent(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"reasoning\",\"summary\":[],\"content\":[{\"type\":\"reasoning_text\",\"text\":\"Check the tests first\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Done\"}]}}\n" +
+		"{\"type\":\"reasoning\",\"id\":\"rs_1\",\"content\":[{\"type\":\"reasoning_text\",\"text\":\"Direct format\"}],\"encrypted_content\":\"gAAAAABvdGhlcg==\"}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 3 {
+		t.Fatalf("expected reasoning, message, reasoning; got %d items", len(session.Items))
+	}
+	for i, want := range map[int]string{0: "Check the tests first", 2: "Direct format"} {
+		if item := session.Items[i]; item.Hidden || item.Content != want {
+			t.Fatalf("item %d: expected plaintext reasoning %q, got %+v", i, want, item)
+		}
+	}
+}
+
+func TestParseSessionAbortedTurns(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Start\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"turn_aborted\",\"reason\":\"interrupted\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"<turn_aborted>The user interrupted the previous turn.</turn_aborted>\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:05Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Stopping\"}]}}\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(session.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(session.Items))
+	}
+	if session.Items[0].Aborted {
+		t.Fatalf("expected the first user message to be unflagged")
+	}
+	event := session.Items[1]
+	if !event.Aborted || event.Subtype != "turn_aborted" || event.Content != "Turn aborted: interrupted" {
+		t.Fatalf("expected an aborted event item, got %+v", event)
+	}
+	marker := session.Items[2]
+	if !marker.Aborted || !strings.Contains(marker.Content, "<turn_aborted>") {
+		t.Fatalf("expected the injected marker to be flagged and kept verbatim, got %+v", marker)
+	}
+	if session.Items[3].Aborted {
+		t.Fatalf("expected the assistant reply to be unflagged")
+	}
+}
+
+func TestMergeConsecutiveKeepsAborted(t *testing.T) {
+	items := []RenderItem{
+		{Type: "event_msg", Subtype: "turn_aborted", Role: "system", Content: "Turn aborted", Aborted: true},
+		{Type: "event_msg", Subtype: "turn_aborted", Role: "system", Content: "Turn aborted: interrupted"},
+	}
+	merged := mergeConsecutive(items)
+	if len(merged) != 1 || !merged[0].Aborted {
+		t.Fatalf("expected one aborted item after merge, got %+v", merged)
+	}
+}
+
+func TestTrimUserRequestMarkers(t *testing.T) {
+	defer SetTrimUserRequestMarkers([]string{DefaultTrimMarker})
+	defer SetTrimFromLastMarker(false)
+
+	const nested = "Outer\n\n## My request for Codex:\nWrap this\n\n## My request for Codex:\nInner"
+	const mixed = "Context\n\n## Mi pedido:\nSolo esto\n\n## My request for Codex:\nOnly this"
+	cases := []struct {
+		name    string
+		markers []string
+		last    bool
+		content string
+		want    string
+	}{
+		{"no marker", []string{DefaultTrimMarker}, false, "Just a question", "Just a question"},
+		{"one marker", []string{DefaultTrimMarker}, false, "Files\n\n## My request for Codex:\nFix it", "Fix it"},
+		{"repeated marker, first", []string{DefaultTrimMarker}, false, nested, "Wrap this\n\n## My request for Codex:\nInner"},
+		{"repeated marker, last", []string{DefaultTrimMarker}, true, nested, "Inner"},
+		{"custom marker", []string{"## Mi pedido:"}, false, mixed, "Solo esto\n\n## My request for Codex:\nOnly this"},
+		{"several markers, earliest wins", []string{DefaultTrimMarker, "## Mi pedido:"}, false, mixed, "Solo esto\n\n## My request for Codex:\nOnly this"},
+		{"several markers, latest wins", []string{"## Mi pedido:", DefaultTrimMarker}, true, mixed, "Only this"},
+		{"only the absent marker", []string{"## Absent:"}, false, mixed, mixed},
+		{"no markers configured", nil, false, mixed, mixed},
+	}
+	for _, tc := range cases {
+		SetTrimUserRequestMarkers(tc.markers)
+		SetTrimFromLastMarker(tc.last)
+		if got := trimUserRequest(tc.content); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseSessionTypeCounts(t *testing.T) {
+	base := t.TempDir()
+	filePath := filepath.Join(base, "session.jsonl")
+	data := "" +
+		"{\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\"}}\n" +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hi\"}]}}\n" +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{}\",\"call_id\":\"c1\"}}\n" +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"c1\",\"output\":\"ok\"}}\n" +
+		"{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"c1\",\"output\":\"ok\"}}\n" +
+		"{\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\"}}\n" +
+		"{\"type\":\"reasoning\",\"summary\":[]}\n" +
+		"not-json\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	session, err := ParseSession(filePath)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[EnvelopeType]int{
+		{Type: "session_meta"}:                                   1,
+		{Type: "response_item", Subtype: "message"}:              1,
+		{Type: "response_item", Subtype: "function_call"}:        1,
+		{Type: "response_item", Subtype: "function_call_output"}: 2,
+		{Type: "event_msg", Subtype: "token_count"}:              1,
+		{Type: "reasoning"}:                                      1,
+	}
+	if len(session.TypeCounts) != len(want) {
+		t.Fatalf("unexpected type counts: %v", session.TypeCounts)
+	}
+	for kind, count := range want {
+		if session.TypeCounts[kind] != count {
+			t.Fatalf("expected %d %v lines, got %v", count, kind, session.TypeCounts)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package sessions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultPathPattern is the Codex layout: sessions/{year}/{month}/{day}/file.jsonl.
+const DefaultPathPattern = "{year}/{month}/{day}"
+
+// PathPattern describes the directory layout below the sessions root. It is a
+// slash-separated template where {year}, {month}, and {day} each appear once;
+// any other {name} matches a single path segment and is ignored.
+type PathPattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+var placeholderRE = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// ParsePathPattern compiles a layout such as "{year}-{month}-{day}" or
+// "{account}/{year}/{month}/{day}".
+func ParsePathPattern(pattern string) (PathPattern, error) {
+	pattern = strings.Trim(strings.TrimSpace(pattern), "/")
+	if pattern == "" {
+		return PathPattern{}, fmt.Errorf("path pattern is empty")
+	}
+	seen := map[string]int{}
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range placeholderRE.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern[last:loc[0]]))
+		name := pattern[loc[2]:loc[3]]
+		seen[name]++
+		switch name {
+		case "year":
+			expr.WriteString(`(?P<year>\d{4})`)
+		case "month":
+			expr.WriteString(`(?P<month>\d{2})`)
+		case "day":
+			expr.WriteString(`(?P<day>\d{2})`)
+		default:
+			expr.WriteString(`[^/]+`)
+		}
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[last:]))
+	expr.WriteString(`/(?P<file>[^/]+)$`)
+	for _, name := range []string{"year", "month", "day"} {
+		if seen[name] != 1 {
+			return PathPattern{}, fmt.Errorf("path pattern %q must contain {%s} exactly once", pattern, name)
+		}
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return PathPattern{}, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+	}
+	return PathPattern{raw: pattern, re: re}, nil
+}
+
+func (p PathPattern) String() string {
+	return p.raw
+}
+
+// Match extracts the date and file name from a slash-separated path relative
+// to the sessions root.
+func (p PathPattern) Match(rel string) (DateKey, string, bool) {
+	m := p.re.FindStringSubmatch(rel)
+	if m == nil {
+		return DateKey{}, "", false
+	}
+	date, ok := ParseDate(m[p.re.SubexpIndex("year")], m[p.re.SubexpIndex("month")], m[p.re.SubexpIndex("day")])
+	if !ok {
+		return DateKey{}, "", false
+	}
+	return date, m[p.re.SubexpIndex("file")], true
+}
+
+var defaultPathPattern = func() PathPattern {
+	p, err := ParsePathPattern(DefaultPathPattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}()
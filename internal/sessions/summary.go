@@ -0,0 +1,93 @@
+package sessions
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// summaryMaxRunes caps SessionFile.Summary.
+	summaryMaxRunes = 80
+	// summaryMaxLines bounds how far into a file ParseSessionSummary reads.
+	summaryMaxLines = 200
+)
+
+// ParseSessionSummary returns the first meaningful user message of a session,
+// trimmed like the session view (request marker, auto context skipped) and
+// shortened to one line of at most summaryMaxRunes runes.
+func ParseSessionSummary(path string) (string, error) {
+	head, err := parseSessionHead(path)
+	return head.summary, err
+}
+
+// sessionHead is what the index keeps from the first summaryMaxLines lines.
+type sessionHead struct {
+	summary string
+	// hasItems reports whether the file holds anything beyond session_meta
+	// and auto-context user messages; a file cut off by the line limit
+	// counts as non-empty.
+	hasItems bool
+	kind     SessionKind
+}
+
+// parseSessionHead reads at most summaryMaxLines lines for the summary,
+// emptiness, and kind of a session.
+func parseSessionHead(path string) (sessionHead, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return sessionHead{}, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	scratch := &Session{Path: path}
+	var head sessionHead
+	var histogram kindHistogram
+	for lineNum := 1; lineNum <= summaryMaxLines; lineNum++ {
+		line, err := reader.ReadBytes('\n')
+		if isPartialTrailingLine(line, err) {
+			head.kind = histogram.classify(true)
+			return head, nil
+		}
+		if len(line) > 0 {
+			lineText := strings.TrimRight(string(line), "\r\n")
+			if lineNum == 1 {
+				lineText = strings.TrimPrefix(lineText, utf8BOM)
+			}
+			item := parseLine(lineText, lineNum, scratch)
+			if item != nil {
+				// Injected context alone (AGENTS.md, environment_context)
+				// is not a conversation yet.
+				if item.Role != "user" || !IsAutoContextUserMessage(item.Content) {
+					head.hasItems = true
+				}
+				histogram.add(item)
+			}
+			if item != nil && head.summary == "" && item.Role == "user" && !IsAutoContextUserMessage(item.Content) {
+				head.summary = summarize(item.Content)
+			}
+		}
+		if err == io.EOF {
+			head.kind = histogram.classify(true)
+			return head, nil
+		}
+		if err != nil {
+			return head, err
+		}
+	}
+	head.hasItems = true
+	head.kind = histogram.classify(false)
+	return head, nil
+}
+
+func summarize(content string) string {
+	text := strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(text) <= summaryMaxRunes {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:summaryMaxRunes-1])) + "…"
+}
@@ -0,0 +1,244 @@
+package sessions
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// UnknownModel labels usage from sessions that never recorded a model.
+const UnknownModel = "(unknown)"
+
+// TokenUsage mirrors the token counters Codex records in token_count events.
+type TokenUsage struct {
+	InputTokens           int64 `json:"input_tokens"`
+	CachedInputTokens     int64 `json:"cached_input_tokens"`
+	OutputTokens          int64 `json:"output_tokens"`
+	ReasoningOutputTokens int64 `json:"reasoning_output_tokens"`
+	TotalTokens           int64 `json:"total_tokens"`
+}
+
+// Add accumulates other into u.
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.CachedInputTokens += other.CachedInputTokens
+	u.OutputTokens += other.OutputTokens
+	u.ReasoningOutputTokens += other.ReasoningOutputTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// IsZero reports whether no tokens were recorded.
+func (u TokenUsage) IsZero() bool {
+	return u == TokenUsage{}
+}
+
+// SessionUsage is the token usage recorded in one session file.
+type SessionUsage struct {
+	Model string     `json:"model"`
+	Usage TokenUsage `json:"usage"`
+}
+
+type tokenCountPayload struct {
+	Type string `json:"type"`
+	Info *struct {
+		TotalTokenUsage *TokenUsage `json:"total_token_usage"`
+	} `json:"info"`
+	TokenUsage
+}
+
+type turnContextPayload struct {
+	Model string `json:"model"`
+}
+
+// ParseSessionUsage scans a session for token_count events and the model from
+// turn_context entries. total_token_usage is cumulative, so the last one wins;
+// older files without it report per-turn counts, which are summed.
+func ParseSessionUsage(path string) (SessionUsage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return SessionUsage{}, err
+	}
+	defer file.Close()
+
+	var out SessionUsage
+	var summed TokenUsage
+	var cumulative *TokenUsage
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case bytes.Contains(line, []byte(`"token_count"`)):
+				var env envelope
+				var payload tokenCountPayload
+				if json.Unmarshal(line, &env) == nil && json.Unmarshal(env.Payload, &payload) == nil && payload.Type == "token_count" {
+					if payload.Info != nil && payload.Info.TotalTokenUsage != nil {
+						total := *payload.Info.TotalTokenUsage
+						cumulative = &total
+					} else {
+						summed.Add(payload.TokenUsage)
+					}
+				}
+			case bytes.Contains(line, []byte(`"turn_context"`)):
+				var env envelope
+				var payload turnContextPayload
+				if json.Unmarshal(line, &env) == nil && env.Type == "turn_context" && json.Unmarshal(env.Payload, &payload) == nil {
+					if model := strings.TrimSpace(payload.Model); model != "" {
+						out.Model = model
+					}
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return SessionUsage{}, err
+		}
+	}
+	if cumulative != nil {
+		out.Usage = *cumulative
+	} else {
+		out.Usage = summed
+	}
+	if out.Model == "" {
+		out.Model = UnknownModel
+	}
+	return out, nil
+}
+
+// ModelPrice is the USD cost per million tokens for a model.
+// A zero CachedInput falls back to the Input price.
+type ModelPrice struct {
+	Input       float64 `json:"input"`
+	CachedInput float64 `json:"cached_input"`
+	Output      float64 `json:"output"`
+}
+
+// PriceTable maps model names (or name prefixes) to prices.
+type PriceTable map[string]ModelPrice
+
+// LoadPriceTable reads a JSON object of model name to ModelPrice.
+func LoadPriceTable(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var table PriceTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// Lookup finds the price for model, preferring an exact match and then the
+// longest matching prefix (so "gpt-5" also prices "gpt-5-codex").
+func (t PriceTable) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := t[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range t {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return t[best], true
+}
+
+// Cost estimates the USD cost of usage at this price.
+func (p ModelPrice) Cost(usage TokenUsage) float64 {
+	cachedRate := p.CachedInput
+	if cachedRate == 0 {
+		cachedRate = p.Input
+	}
+	uncached := usage.InputTokens - usage.CachedInputTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*p.Input + float64(usage.CachedInputTokens)*cachedRate + float64(usage.OutputTokens)*p.Output) / 1e6
+}
+
+// ModelUsage is the aggregated usage for one model.
+type ModelUsage struct {
+	Model    string     `json:"model"`
+	Sessions int        `json:"sessions"`
+	Usage    TokenUsage `json:"usage"`
+	Cost     float64    `json:"cost"`
+	Priced   bool       `json:"priced"`
+}
+
+// UsageReport sums token usage over a date range.
+type UsageReport struct {
+	From          string       `json:"from,omitempty"`
+	To            string       `json:"to,omitempty"`
+	Sessions      int          `json:"sessions"`
+	Total         TokenUsage   `json:"total"`
+	EstimatedCost float64      `json:"estimated_cost"`
+	Models        []ModelUsage `json:"models"`
+}
+
+// AggregateUsage parses usage from every indexed session whose date falls in
+// [from, to]; a zero DateKey leaves that side of the range open.
+func AggregateUsage(idx *Index, from, to DateKey, prices PriceTable) (UsageReport, error) {
+	report := UsageReport{Models: []ModelUsage{}}
+	if from != (DateKey{}) {
+		report.From = from.String()
+	}
+	if to != (DateKey{}) {
+		report.To = to.String()
+	}
+	byModel := map[string]*ModelUsage{}
+	for _, date := range idx.Dates() {
+		label := date.String()
+		if report.From != "" && label < report.From {
+			continue
+		}
+		if report.To != "" && label > report.To {
+			continue
+		}
+		for _, file := range idx.SessionsByDate(date) {
+			usage, err := ParseSessionUsage(file.Path)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return UsageReport{}, err
+			}
+			if usage.Usage.IsZero() {
+				continue
+			}
+			model := byModel[usage.Model]
+			if model == nil {
+				model = &ModelUsage{Model: usage.Model}
+				byModel[usage.Model] = model
+			}
+			model.Sessions++
+			model.Usage.Add(usage.Usage)
+			report.Sessions++
+			report.Total.Add(usage.Usage)
+		}
+	}
+	for _, model := range byModel {
+		if price, ok := prices.Lookup(model.Model); ok {
+			model.Priced = true
+			model.Cost = price.Cost(model.Usage)
+			report.EstimatedCost += model.Cost
+		}
+		report.Models = append(report.Models, *model)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		if report.Models[i].Usage.TotalTokens == report.Models[j].Usage.TotalTokens {
+			return report.Models[i].Model < report.Models[j].Model
+		}
+		return report.Models[i].Usage.TotalTokens > report.Models[j].Usage.TotalTokens
+	})
+	return report, nil
+}
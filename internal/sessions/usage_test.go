@@ -0,0 +1,79 @@
+package sessions
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func writeUsageSession(t *testing.T, base, datePath, name, model string, input, cached, output int64) {
+	t.Helper()
+	dir := filepath.Join(base, filepath.FromSlash(datePath))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data := "{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"turn_context\",\"payload\":{\"cwd\":\"/tmp\",\"model\":\"" + model + "\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\",\"info\":{\"total_token_usage\":{\"input_tokens\":1,\"output_tokens\":1,\"total_tokens\":2}}}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\",\"info\":{\"total_token_usage\":{" +
+		"\"input_tokens\":" + strconv.FormatInt(input, 10) + ",\"cached_input_tokens\":" + strconv.FormatInt(cached, 10) + ",\"output_tokens\":" + strconv.FormatInt(output, 10) + ",\"total_tokens\":" + strconv.FormatInt(input+output, 10) + "}}}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\",\"info\":null}}\n"
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestParseSessionUsage(t *testing.T) {
+	base := t.TempDir()
+	writeUsageSession(t, base, "2026/01/09", "a.jsonl", "gpt-5-codex", 1000, 400, 200)
+	usage, err := ParseSessionUsage(filepath.Join(base, "2026", "01", "09", "a.jsonl"))
+	if err != nil {
+		t.Fatalf("parse usage: %v", err)
+	}
+	if usage.Model != "gpt-5-codex" {
+		t.Fatalf("expected model gpt-5-codex, got %q", usage.Model)
+	}
+	want := TokenUsage{InputTokens: 1000, CachedInputTokens: 400, OutputTokens: 200, TotalTokens: 1200}
+	if usage.Usage != want {
+		t.Fatalf("expected last cumulative usage %+v, got %+v", want, usage.Usage)
+	}
+}
+
+func TestAggregateUsage(t *testing.T) {
+	base := t.TempDir()
+	writeUsageSession(t, base, "2026/01/09", "a.jsonl", "gpt-5-codex", 1000000, 0, 100000)
+	writeUsageSession(t, base, "2026/01/10", "b.jsonl", "gpt-5-codex", 1000000, 500000, 0)
+	writeUsageSession(t, base, "2026/01/11", "c.jsonl", "mystery", 10, 0, 10)
+
+	idx := NewIndex(base)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	prices := PriceTable{"gpt-5": {Input: 1, CachedInput: 0.1, Output: 10}}
+
+	report, err := AggregateUsage(idx, DateKey{}, DateKey{}, prices)
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if report.Sessions != 3 || len(report.Models) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	// a: 1M input + 100k output = $1 + $1; b: 500k uncached + 500k cached = $0.5 + $0.05.
+	if math.Abs(report.EstimatedCost-2.55) > 1e-9 {
+		t.Fatalf("expected cost 2.55, got %v", report.EstimatedCost)
+	}
+	if report.Models[1].Model != "mystery" || report.Models[1].Priced {
+		t.Fatalf("expected unpriced mystery model, got %+v", report.Models[1])
+	}
+
+	from, _ := ParseDate("2026", "01", "10")
+	to, _ := ParseDate("2026", "01", "10")
+	report, err = AggregateUsage(idx, from, to, prices)
+	if err != nil {
+		t.Fatalf("aggregate range: %v", err)
+	}
+	if report.Sessions != 1 || report.Total.InputTokens != 1000000 {
+		t.Fatalf("unexpected ranged report: %+v", report)
+	}
+}
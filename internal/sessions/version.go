@@ -0,0 +1,66 @@
+package sessions
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CliVersionForFile returns the Codex CLI version recorded in file's metadata.
+func CliVersionForFile(file SessionFile) string {
+	if file.Meta == nil {
+		return ""
+	}
+	return strings.TrimSpace(file.Meta.CliVersion)
+}
+
+// CompareCliVersions orders dotted versions such as "0.46.0" numerically,
+// returning -1, 0, or 1. A pre-release suffix ("0.47.0-alpha.1") sorts before
+// the release; non-numeric parts fall back to string order.
+func CompareCliVersions(a, b string) int {
+	coreA, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	coreB, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var pa, pb string
+		if i < len(partsA) {
+			pa = partsA[i]
+		}
+		if i < len(partsB) {
+			pb = partsB[i]
+		}
+		if c := compareVersionPart(pa, pb); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return strings.Compare(preA, preB)
+}
+
+func compareVersionPart(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	if a == "" {
+		na, errA = 0, nil
+	}
+	if b == "" {
+		nb, errB = 0, nil
+	}
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+	switch {
+	case na < nb:
+		return -1
+	case na > nb:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+const (
+	defaultActivityDays = 30
+	maxActivityDays     = 366
+
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+type activityDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// sparklineView is a precomputed SVG polyline for the dir page.
+type sparklineView struct {
+	Days   int
+	Total  int
+	Points string
+	Width  int
+	Height int
+}
+
+// cwdActivity counts sessions for cwd on each of the last days calendar days
+// (oldest first, ending today), zero-filling days without sessions.
+func (s *Server) cwdActivity(cwd string, days int, now time.Time) []activityDay {
+	counts := map[string]int{}
+	for _, file := range s.idx.SessionsByCwd(cwd) {
+		counts[file.Date.String()]++
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	out := make([]activityDay, days)
+	for i := range out {
+		label := today.AddDate(0, 0, i-days+1).Format("2006-01-02")
+		out[i] = activityDay{Date: label, Count: counts[label]}
+	}
+	return out
+}
+
+func (s *Server) handleCwdActivity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	cwd := normalizeCwdParam(r.URL.Query().Get("cwd"))
+	if cwd == "" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("cwd is required (use %s for sessions without one)", sessions.UnknownCwd))
+		return
+	}
+	days := intParam(r, "days")
+	if days == 0 {
+		days = defaultActivityDays
+	}
+	days = min(days, maxActivityDays)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.cwdActivity(cwd, days, time.Now()))
+}
+
+// buildSparkline scales activity into polyline points; an idle window is a
+// flat line along the bottom.
+func buildSparkline(activity []activityDay) sparklineView {
+	view := sparklineView{Days: len(activity), Width: sparklineWidth, Height: sparklineHeight}
+	peak := 0
+	for _, day := range activity {
+		view.Total += day.Count
+		peak = max(peak, day.Count)
+	}
+	points := make([]string, 0, len(activity))
+	for i, day := range activity {
+		x := 0.0
+		if len(activity) > 1 {
+			x = float64(i) * sparklineWidth / float64(len(activity)-1)
+		}
+		y := float64(sparklineHeight - 1)
+		if peak > 0 {
+			y = 1 + float64(sparklineHeight-2)*(1-float64(day.Count)/float64(peak))
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	view.Points = strings.Join(points, " ")
+	return view
+}
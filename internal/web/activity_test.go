@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+func TestCwdActivityZeroFills(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/proj", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/07", "c.jsonl", "", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/01", "old.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	now := time.Date(2026, 1, 10, 15, 0, 0, 0, time.Local)
+	got := server.cwdActivity("/proj", 4, now)
+	want := []activityDay{{"2026-01-07", 0}, {"2026-01-08", 0}, {"2026-01-09", 2}, {"2026-01-10", 0}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d days, got %v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("day %d: got %v want %v", i, got[i], want[i])
+		}
+	}
+	unknown := server.cwdActivity(sessions.UnknownCwd, 4, now)
+	if unknown[0].Count != 1 {
+		t.Fatalf("expected the cwd-less session under %s, got %v", sessions.UnknownCwd, unknown)
+	}
+
+	spark := buildSparkline(got)
+	if spark.Total != 2 || len(strings.Fields(spark.Points)) != 4 {
+		t.Fatalf("unexpected sparkline: %+v", spark)
+	}
+}
+
+func TestHandleCwdActivity(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cwd-activity", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without cwd, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cwd-activity?cwd=/proj&days=1000", nil))
+	var days []activityDay
+	if err := json.Unmarshal(rec.Body.Bytes(), &days); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(days) != maxActivityDays || days[len(days)-1].Date != time.Now().Format("2006-01-02") {
+		t.Fatalf("expected %d days ending today, got %d ending %v", maxActivityDays, len(days), days[len(days)-1])
+	}
+}
@@ -0,0 +1,211 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"codex-manager/internal/sessions"
+)
+
+type archivedFileView struct {
+	Date dateView
+	Name string
+	// Path is the file's slash-separated path below the archive dir, which
+	// is also where it lived below the sessions dir.
+	Path    string
+	Size    string
+	ModTime string
+}
+
+type archivePageView struct {
+	Files      []archivedFileView
+	ArchiveDir string
+	ThemeClass string
+	ThemeVars  template.CSS
+	ReadOnly   bool
+}
+
+// EnableArchive turns on the archive action, which moves sessions into dir
+// instead of deleting them, keeping their path below the sessions dir so a
+// restore puts them back exactly where they were (-path-pattern included).
+func (s *Server) EnableArchive(dir string) {
+	s.archiveDir = dir
+}
+
+// handleArchive serves GET /archive, POST /archive/<date>/<file> and
+// POST /archive/restore/<path>, where path is the file's path below the
+// archive dir.
+func (s *Server) handleArchive(w http.ResponseWriter, r *http.Request, rest string) {
+	if s.archiveDir == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if rest == "" {
+		s.handleArchivePage(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	var src, dst, redirect string
+	if trimmed, ok := strings.CutPrefix(rest, "restore/"); ok {
+		rel, ok := s.archivedPath(trimmed)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		src = filepath.Join(s.archiveDir, rel)
+		dst = filepath.Join(s.sessionsDir, rel)
+		redirect = "/archive"
+	} else {
+		parts := strings.Split(rest, "/")
+		if len(parts) != 4 {
+			http.NotFound(w, r)
+			return
+		}
+		date, ok := sessions.ParseDate(parts[0], parts[1], parts[2])
+		if !ok || !safeFilename(parts[3]) {
+			http.NotFound(w, r)
+			return
+		}
+		file, ok := s.idx.Lookup(date, parts[3])
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		rel, err := filepath.Rel(s.sessionsDir, file.Path)
+		if err != nil || !filepath.IsLocal(rel) {
+			http.Error(w, fmt.Sprintf("%s is outside the sessions dir", file.Path), http.StatusInternalServerError)
+			return
+		}
+		src = file.Path
+		dst = filepath.Join(s.archiveDir, rel)
+		redirect = "/" + date.Path() + "/"
+	}
+	if err := moveSessionFile(src, dst); err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			http.NotFound(w, r)
+		case errors.Is(err, fs.ErrExist):
+			http.Error(w, fmt.Sprintf("%s already exists", dst), http.StatusConflict)
+		default:
+			http.Error(w, fmt.Sprintf("failed to move session: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	// A rescan already running may miss the move; the next tick catches it.
+	// A search reindex error concerns some other file, as in handleRefresh;
+	// the sessions index itself is up to date, so the move still succeeded.
+	if _, err := s.Rescan(); err != nil && !errors.Is(err, ErrRefreshInProgress) && !errors.Is(err, ErrSearchReindex) {
+		http.Error(w, fmt.Sprintf("session moved, but rescanning failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// archivedPath checks a slash-separated path below the archive dir against
+// the sessions layout and returns it in OS form.
+func (s *Server) archivedPath(rel string) (string, bool) {
+	for _, segment := range strings.Split(rel, "/") {
+		if !safeFilename(segment) {
+			return "", false
+		}
+	}
+	if _, name, ok := s.idx.PathPattern().Match(rel); !ok || !safeFilename(name) {
+		return "", false
+	}
+	return filepath.FromSlash(rel), true
+}
+
+// moveSessionFile renames src to dst, creating dst's directory. It refuses to
+// overwrite an existing file.
+func moveSessionFile(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fs.ErrExist
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+func (s *Server) handleArchivePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	files, err := s.listArchivedFiles()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+	view := archivePageView{
+		Files:      files,
+		ArchiveDir: s.archiveDir,
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+		ReadOnly:   s.readOnly,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.renderer.Execute(w, "archive", view)
+}
+
+// listArchivedFiles returns archived sessions laid out like the sessions dir
+// (see -path-pattern), newest date first. Anything else under the archive dir
+// is ignored.
+func (s *Server) listArchivedFiles() ([]archivedFileView, error) {
+	var files []archivedFileView
+	err := filepath.WalkDir(s.archiveDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == s.archiveDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.archiveDir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if _, ok := s.archivedPath(rel); !ok {
+			return nil
+		}
+		date, name, _ := s.idx.PathPattern().Match(rel)
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, archivedFileView{
+			Date:    dateView{Label: date.String(), Path: date.Path()},
+			Name:    name,
+			Path:    rel,
+			Size:    formatBytes(info.Size()),
+			ModTime: s.formatTime(info.ModTime()),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Date.Path != files[j].Date.Path {
+			return files[i].Date.Path > files[j].Date.Path
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files, nil
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+func TestArchiveAndRestoreSession(t *testing.T) {
+	sessionsDir := t.TempDir()
+	archiveDir := filepath.Join(t.TempDir(), "archive")
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/tmp", time.Now())
+	server := newTestServer(t, sessionsDir)
+	date, _ := sessions.ParseDate("2026", "01", "09")
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/archive/2026/01/09/a.jsonl"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while archiving is disabled, got %d", rec.Code)
+	}
+	server.EnableArchive(archiveDir)
+
+	rec := post("/archive/2026/01/09/a.jsonl")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/2026/01/09/" {
+		t.Fatalf("archive: got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if _, err := os.Stat(filepath.Join(archiveDir, "2026", "01", "09", "a.jsonl")); err != nil {
+		t.Fatalf("expected archived file: %v", err)
+	}
+	if _, ok := server.idx.Lookup(date, "a.jsonl"); ok {
+		t.Fatalf("expected archived session to leave the index")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/archive", nil)
+	page := httptest.NewRecorder()
+	server.ServeHTTP(page, req)
+	if page.Code != http.StatusOK || !strings.Contains(page.Body.String(), `action="/archive/restore/2026/01/09/a.jsonl"`) {
+		t.Fatalf("expected restore form on archive page, got %d: %s", page.Code, page.Body.String())
+	}
+
+	// A new session with the same name blocks the restore instead of being overwritten.
+	blocker := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/other", time.Now())
+	if rec := post("/archive/restore/2026/01/09/a.jsonl"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when the target exists, got %d", rec.Code)
+	}
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	rec = post("/archive/restore/2026/01/09/a.jsonl")
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/archive" {
+		t.Fatalf("restore: got %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if _, ok := server.idx.Lookup(date, "a.jsonl"); !ok {
+		t.Fatalf("expected restored session back in the index")
+	}
+
+	for _, path := range []string{"/archive/2026/01/09/..", "/archive/2026/13/09/a.jsonl", "/archive/2026/01/09/missing.jsonl"} {
+		if rec := post(path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestArchiveKeepsPathPatternLayout(t *testing.T) {
+	sessionsDir := t.TempDir()
+	archiveDir := filepath.Join(t.TempDir(), "archive")
+	writeSessionWithCwd(t, sessionsDir, "work/2026/01/09", "a.jsonl", "/work", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "home/2026/01/09", "b.jsonl", "/home", time.Now())
+	server := newTestServer(t, sessionsDir)
+	pattern, err := sessions.ParsePathPattern("{account}/{year}/{month}/{day}")
+	if err != nil {
+		t.Fatalf("pattern: %v", err)
+	}
+	server.idx.SetPathPattern(pattern)
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	server.EnableArchive(archiveDir)
+
+	post := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post("/archive/2026/01/09/a.jsonl", "Origin", "https://evil.example"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a cross-site archive, got %d", rec.Code)
+	}
+	if rec := post("/archive/2026/01/09/a.jsonl"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("archive: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(archiveDir, "work", "2026", "01", "09", "a.jsonl")); err != nil {
+		t.Fatalf("expected the account segment kept in the archive: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/archive", nil)
+	page := httptest.NewRecorder()
+	server.ServeHTTP(page, req)
+	if !strings.Contains(page.Body.String(), `action="/archive/restore/work/2026/01/09/a.jsonl"`) {
+		t.Fatalf("expected a restore form for the archived path, got %s", page.Body.String())
+	}
+
+	if rec := post("/archive/restore/work/2026/01/09/a.jsonl", "Sec-Fetch-Site", "cross-site"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a cross-site restore, got %d", rec.Code)
+	}
+	for _, path := range []string{"/archive/restore/work/2026/01/../a.jsonl", "/archive/restore/2026/01/09/a.jsonl", "/archive/restore/work/2026/01/09/missing.jsonl"} {
+		if rec := post(path); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+	if rec := post("/archive/restore/work/2026/01/09/a.jsonl"); rec.Code != http.StatusSeeOther {
+		t.Fatalf("restore: got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "work", "2026", "01", "09", "a.jsonl")); err != nil {
+		t.Fatalf("expected the session restored to its account dir: %v", err)
+	}
+	date, _ := sessions.ParseDate("2026", "01", "09")
+	if _, ok := server.idx.Lookup(date, "a.jsonl"); !ok {
+		t.Fatalf("expected the restored session back in the index")
+	}
+}
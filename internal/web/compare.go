@@ -0,0 +1,128 @@
+package web
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type compareSideView struct {
+	Param string
+	Date  dateView
+	File  sessionView
+}
+
+// compareCellView is one session's side of a row; Href links back to the
+// session page so line anchors can be appended.
+type compareCellView struct {
+	Href  string
+	Items []itemView
+}
+
+// compareRowView pairs one turn (a user message and everything up to the
+// next one) from each session. Either cell may be empty.
+type compareRowView struct {
+	Turn  int
+	Cells [2]compareCellView
+}
+
+type compareView struct {
+	Sides      [2]compareSideView
+	Rows       []compareRowView
+	Align      string
+	ThemeClass string
+	ThemeVars  template.CSS
+}
+
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	align := query.Get("align")
+	if align != "none" {
+		align = "turn"
+	}
+
+	var sides [2]compareSideView
+	var items [2][]itemView
+	for i, name := range []string{"a", "b"} {
+		raw := strings.TrimSpace(query.Get(name))
+		key, ok := parseSessionKey(raw)
+		if !ok {
+			http.Error(w, name+" must be <yyyy-mm-dd>/<name>", http.StatusBadRequest)
+			return
+		}
+		view, err := s.buildSessionView(strings.Split(key, "/"), 0, false)
+		if errors.Is(err, errSessionTooLarge) {
+			http.Error(w, name+": "+err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		sides[i] = compareSideView{Param: raw, Date: view.Date, File: view.File}
+		items[i] = view.Items
+	}
+
+	view := compareView{
+		Sides:      sides,
+		Rows:       compareRows(items, align == "turn"),
+		Align:      align,
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+	}
+	for i := range view.Rows {
+		for side := range view.Rows[i].Cells {
+			view.Rows[i].Cells[side].Href = "/" + sides[side].Date.Path + "/" + sides[side].File.Name
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.renderer.Execute(w, "compare", view)
+}
+
+// compareRows lines the two item lists up by turn index, or puts everything
+// in a single row when byTurn is false.
+func compareRows(items [2][]itemView, byTurn bool) []compareRowView {
+	if !byTurn {
+		return []compareRowView{{Turn: 1, Cells: [2]compareCellView{{Items: items[0]}, {Items: items[1]}}}}
+	}
+	left := splitTurns(items[0])
+	right := splitTurns(items[1])
+	rows := make([]compareRowView, max(len(left), len(right)))
+	for i := range rows {
+		rows[i].Turn = i + 1
+		if i < len(left) {
+			rows[i].Cells[0].Items = left[i]
+		}
+		if i < len(right) {
+			rows[i].Cells[1].Items = right[i]
+		}
+	}
+	return rows
+}
+
+// splitTurns starts a new turn at each real (non auto-context) user message;
+// anything before the first one, such as injected context, joins turn one.
+func splitTurns(items []itemView) [][]itemView {
+	var turns [][]itemView
+	var current []itemView
+	seenUser := false
+	for _, item := range items {
+		if item.IsUser && !item.AutoCtx {
+			if seenUser {
+				turns = append(turns, current)
+				current = nil
+			}
+			seenUser = true
+		}
+		current = append(current, item)
+	}
+	if len(current) > 0 {
+		turns = append(turns, current)
+	}
+	return turns
+}
@@ -0,0 +1,75 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleCompare(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "first.jsonl", "/tmp/one", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "second.jsonl", "/tmp/two", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	cases := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"both", "a=2026-01-09/first.jsonl&b=2026/01/10/second.jsonl", http.StatusOK},
+		{"unaligned", "a=2026-01-09/first.jsonl&b=2026-01-10/second.jsonl&align=none", http.StatusOK},
+		{"missing b", "a=2026-01-09/first.jsonl", http.StatusBadRequest},
+		{"bad name", "a=2026-01-09/first.jsonl&b=2026-01-10/..", http.StatusBadRequest},
+		{"unknown session", "a=2026-01-09/first.jsonl&b=2026-01-10/nope.jsonl", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/compare?"+tc.query, nil)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		body := rec.Body.String()
+		for _, want := range []string{"first.jsonl", "second.jsonl", `href="/2026/01/10/second.jsonl#line-2"`} {
+			if !strings.Contains(body, want) {
+				t.Fatalf("%s: expected %q in body", tc.name, want)
+			}
+		}
+	}
+}
+
+func TestCompareRowsAlignsByTurn(t *testing.T) {
+	left := []itemView{
+		{Line: 1, IsUser: true, AutoCtx: true},
+		{Line: 2, IsUser: true},
+		{Line: 3, IsAssistant: true},
+		{Line: 4, IsUser: true},
+		{Line: 5, IsAssistant: true},
+	}
+	right := []itemView{
+		{Line: 1, IsUser: true},
+		{Line: 2, IsAssistant: true},
+	}
+
+	rows := compareRows([2][]itemView{left, right}, true)
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if got := len(rows[0].Cells[0].Items); got != 3 {
+		t.Fatalf("expected auto context kept in the first turn (3 items), got %d", got)
+	}
+	if got := len(rows[1].Cells[1].Items); got != 0 {
+		t.Fatalf("expected empty right cell for turn 2, got %d items", got)
+	}
+
+	rows = compareRows([2][]itemView{left, right}, false)
+	if len(rows) != 1 || len(rows[0].Cells[0].Items) != len(left) {
+		t.Fatalf("expected a single unaligned row, got %+v", rows)
+	}
+}
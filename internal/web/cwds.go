@@ -0,0 +1,37 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// cwdEntry is one /api/cwds row. Sessions without a cwd are listed under
+// sessions.UnknownCwd, which the cwd= parameters of other routes accept.
+type cwdEntry struct {
+	Cwd   string `json:"cwd"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// handleCwds lists known working directories with session counts, sorted
+// with UnknownCwd last; prefix= keeps those starting with it (ignoring case).
+func (s *Server) handleCwds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	prefix := strings.ToLower(r.URL.Query().Get("prefix"))
+	counts := s.idx.CwdCounts()
+	entries := []cwdEntry{}
+	for _, cwd := range s.idx.Cwds() {
+		// A rescan between the two reads may have dropped the directory.
+		count := counts[cwd]
+		if count == 0 || !strings.HasPrefix(strings.ToLower(cwd), prefix) {
+			continue
+		}
+		entries = append(entries, cwdEntry{Cwd: cwd, Label: dirLabel(cwd), Count: count})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
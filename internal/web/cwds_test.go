@@ -0,0 +1,48 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+func TestHandleCwds(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/work/api", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/work/api", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "c.jsonl", "/Work/web", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "d.jsonl", "/home/me", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "e.jsonl", "", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	get := func(target string) []cwdEntry {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, rec.Code)
+		}
+		var entries []cwdEntry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return entries
+	}
+
+	all := get("/api/cwds")
+	if len(all) != 4 || all[0].Cwd != "/Work/web" || all[3].Cwd != sessions.UnknownCwd || all[3].Label != "Unknown (no CWD)" {
+		t.Fatalf("expected sorted cwds with the unknown one last, got %+v", all)
+	}
+	filtered := get("/api/cwds?prefix=/work/")
+	if len(filtered) != 2 || filtered[0].Cwd != "/Work/web" || filtered[1].Cwd != "/work/api" || filtered[1].Count != 2 {
+		t.Fatalf("expected a case-insensitive prefix filter, got %+v", filtered)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/cwds?prefix=/nowhere", nil))
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Fatalf("expected an empty array, got %q", body)
+	}
+}
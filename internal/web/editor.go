@@ -0,0 +1,122 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"codex-manager/internal/sessions"
+)
+
+// editorCommand is a parsed -editor-command; each whitespace-separated field is
+// templated on its own so a cwd containing spaces stays a single argument.
+type editorCommand struct {
+	fields []*template.Template
+}
+
+type editorData struct {
+	Cwd string
+}
+
+// EnableEditor turns on the open-in-editor action using a command template such
+// as "code {{.Cwd}}". Callers must only enable it on loopback listeners.
+func (s *Server) EnableEditor(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return errors.New("editor command is empty")
+	}
+	parsed := make([]*template.Template, 0, len(fields))
+	for i, field := range fields {
+		tmpl, err := template.New(fmt.Sprintf("editor-%d", i)).Option("missingkey=error").Parse(field)
+		if err != nil {
+			return fmt.Errorf("invalid editor command: %w", err)
+		}
+		parsed = append(parsed, tmpl)
+	}
+	s.editor = &editorCommand{fields: parsed}
+	return nil
+}
+
+// args renders the command for cwd. An argument that is exactly the cwd gets
+// a "--" before it, so a cwd starting with "-" is not read as an editor flag.
+func (e *editorCommand) args(cwd string) ([]string, error) {
+	out := make([]string, 0, len(e.fields)+1)
+	for i, tmpl := range e.fields {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, editorData{Cwd: cwd}); err != nil {
+			return nil, err
+		}
+		if i > 0 && buf.String() == cwd && out[len(out)-1] != "--" {
+			out = append(out, "--")
+		}
+		out = append(out, buf.String())
+	}
+	return out, nil
+}
+
+func (s *Server) handleOpen(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if s.editor == nil {
+		writeJSONError(w, http.StatusForbidden, "open in editor is disabled")
+		return
+	}
+	if !isLoopbackRemote(r.RemoteAddr) {
+		writeJSONError(w, http.StatusForbidden, "open in editor is only available from this machine")
+		return
+	}
+
+	date, ok := sessions.ParseDate(parts[0], parts[1], parts[2])
+	if !ok || !safeFilename(parts[3]) {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.idx.Lookup(date, parts[3])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	cwd := sessions.CwdForFile(file)
+	if cwd == sessions.UnknownCwd {
+		writeJSONError(w, http.StatusBadRequest, "session has no working directory")
+		return
+	}
+	if info, err := os.Stat(cwd); err != nil || !info.IsDir() {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("working directory not found: %s", cwd))
+		return
+	}
+
+	args, err := s.editor.args(cwd)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to build editor command: %v", err))
+		return
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = cwd
+	if err := cmd.Start(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to start editor: %v", err))
+		return
+	}
+	go func() { _ = cmd.Wait() }()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"cwd": cwd})
+}
+
+func isLoopbackRemote(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsStreamsReload(t *testing.T) {
+	server := NewServer(nil, nil, nil, "", "", ":8081", 3)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type: %q", ct)
+	}
+
+	// Wait for the handler to register before publishing.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		server.events.mu.Lock()
+		n := len(server.events.subscribers)
+		server.events.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriber never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.PublishNewSessions(nil)
+	server.PublishNewSessions([]string{"2026/01/09/new.jsonl"})
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if strings.TrimSpace(line) != "data: reload" {
+		t.Fatalf("unexpected event line: %q", line)
+	}
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzipCompressesHTML(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, "<p>hello</p>")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Fatalf("expected Vary header, got %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != "<p>hello</p>" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestGzipPassthrough(t *testing.T) {
+	cases := []struct {
+		name        string
+		path        string
+		accept      string
+		contentType string
+	}{
+		{"no accept-encoding", "/", "", "text/html; charset=utf-8"},
+		{"gzip refused", "/", "gzip;q=0", "text/html; charset=utf-8"},
+		{"zip archive", "/shares/download.zip", "gzip", "application/zip"},
+		{"raw gz download", "/raw/2026/01/09/session.jsonl.gz", "gzip", "application/json"},
+	}
+	for _, tc := range cases {
+		handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			_, _ = io.WriteString(w, "payload")
+		}))
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept-Encoding", tc.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Fatalf("%s: expected no encoding, got %q", tc.name, got)
+		}
+		if rec.Body.String() != "payload" {
+			t.Fatalf("%s: unexpected body %q", tc.name, rec.Body.String())
+		}
+	}
+}
+
+func TestGzipKeepsFlusher(t *testing.T) {
+	handler := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Fatalf("expected wrapped writer to implement http.Flusher")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: reload\n\n")
+		w.(http.Flusher).Flush()
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !rec.Flushed || !strings.Contains(rec.Body.String(), "data: reload") {
+		t.Fatalf("expected uncompressed, flushed event stream; got %q", rec.Body.String())
+	}
+}
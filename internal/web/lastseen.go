@@ -0,0 +1,125 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// lastSeenFile is the name of the store inside the state directory.
+const lastSeenFile = "last_seen.json"
+
+// lastSeenStore remembers when each directory page was last opened. Since
+// is when tracking began; it stands in for directories never opened, so
+// enabling the store does not mark every existing directory unread.
+type lastSeenStore struct {
+	mu    sync.Mutex
+	path  string
+	since time.Time
+	seen  map[string]time.Time
+}
+
+type lastSeenState struct {
+	Since time.Time            `json:"since"`
+	Cwds  map[string]time.Time `json:"cwds"`
+}
+
+// EnableStateDir keeps per-directory "last seen" times in dir so the index
+// can flag directories with sessions newer than the last visit.
+func (s *Server) EnableStateDir(dir string) error {
+	store, err := openLastSeenStore(dir, time.Now())
+	if err != nil {
+		return err
+	}
+	s.lastSeen = store
+	return nil
+}
+
+func openLastSeenStore(dir string, now time.Time) (*lastSeenStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	store := &lastSeenStore{path: filepath.Join(dir, lastSeenFile), seen: map[string]time.Time{}}
+	data, err := os.ReadFile(store.path)
+	if errors.Is(err, os.ErrNotExist) {
+		store.since = now
+		return store, store.save()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state lastSeenState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.New(store.path + ": " + err.Error())
+	}
+	store.since = state.Since
+	for cwd, at := range state.Cwds {
+		store.seen[cwd] = at
+	}
+	return store, nil
+}
+
+// markSeen records a visit at time at to cwd, whose newest session was
+// modified at newest, and persists the store. A visit that changes nothing
+// (the directory already counts as seen) is not written.
+func (l *lastSeenStore) markSeen(cwd string, at, newest time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last, ok := l.seen[cwd]
+	if !ok {
+		last = l.since
+	}
+	if !newest.After(last) {
+		return nil
+	}
+	l.seen[cwd] = at
+	return l.save()
+}
+
+// unread reports whether a session modified at newest postdates the last
+// visit to cwd.
+func (l *lastSeenStore) unread(cwd string, newest time.Time) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last, ok := l.seen[cwd]
+	if !ok {
+		last = l.since
+	}
+	return newest.After(last)
+}
+
+// save writes the store through a temporary file so a crash never leaves
+// it half-written. Callers hold l.mu, apart from the first save on open.
+func (l *lastSeenStore) save() error {
+	data, err := json.Marshal(lastSeenState{Since: l.since, Cwds: l.seen})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), lastSeenFile+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirUnreadSinceLastVisit(t *testing.T) {
+	sessionsDir := t.TempDir()
+	stateDir := filepath.Join(t.TempDir(), "state")
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "old.jsonl", "/proj", now.Add(-2*time.Hour))
+	server := newTestServer(t, sessionsDir)
+
+	index := func() string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?view=dir", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		return rec.Body.String()
+	}
+	if strings.Contains(index(), `class="tag tag-unread"`) {
+		t.Fatalf("expected no unread tags without a state dir")
+	}
+
+	store, err := openLastSeenStore(stateDir, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	server.lastSeen = store
+	if strings.Contains(index(), `class="tag tag-unread"`) {
+		t.Fatalf("expected sessions older than the store to count as seen")
+	}
+
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "new.jsonl", "/proj", now.Add(-30*time.Minute))
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if !strings.Contains(index(), `class="tag tag-unread"`) {
+		t.Fatalf("expected a newer session to mark the directory unread")
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/dir?cwd=/proj", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected dir page 200, got %d", rec.Code)
+	}
+	if strings.Contains(index(), `class="tag tag-unread"`) {
+		t.Fatalf("expected opening the dir page to clear the unread tag")
+	}
+
+	before, err := os.ReadFile(filepath.Join(stateDir, lastSeenFile))
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	for _, target := range []string{"/dir?cwd=/proj", "/dir?cwd=/nowhere"} {
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	after, err := os.ReadFile(filepath.Join(stateDir, lastSeenFile))
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Fatalf("expected repeat visits and unknown cwds to leave the store alone:\n%s\n%s", before, after)
+	}
+	if _, ok := store.seen["/nowhere"]; ok {
+		t.Fatalf("expected a cwd without sessions not to be recorded")
+	}
+
+	reopened, err := openLastSeenStore(stateDir, now)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	if !reopened.since.Equal(store.since) {
+		t.Fatalf("expected since to persist, got %v want %v", reopened.since, store.since)
+	}
+	if reopened.unread("/proj", now.Add(-30*time.Minute)) {
+		t.Fatalf("expected the visit to persist")
+	}
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownToHTMLStripsUnsafeMarkup(t *testing.T) {
+	for _, input := range []string{
+		"<script>alert(1)</script>",
+		"hi <img src=x onerror=alert(1)> there",
+		"[x](javascript:alert(1))",
+		"<a href=\"javascript:alert(1)\">x</a>",
+		"<iframe src=\"https://example.com\"></iframe>",
+	} {
+		out := strings.ToLower(string(markdownToHTML(input)))
+		for _, bad := range []string{"<script", "onerror", "javascript:", "<iframe"} {
+			if strings.Contains(out, bad) {
+				t.Fatalf("%q: %q survived in %q", input, bad, out)
+			}
+		}
+	}
+}
+
+func TestMarkdownPolicySanitizesRawHTML(t *testing.T) {
+	// What goldmark would emit if raw HTML were ever let through.
+	out := markdownPolicy.Sanitize(`<p onclick="x()">ok</p><script>alert(1)</script><a href="javascript:alert(1)">l</a><img src="x" onerror="y()">`)
+	for _, bad := range []string{"onclick", "<script", "javascript:", "onerror"} {
+		if strings.Contains(out, bad) {
+			t.Fatalf("%q survived sanitizing: %q", bad, out)
+		}
+	}
+}
+
+func TestMarkdownToHTMLKeepsFormatting(t *testing.T) {
+	input := "```go\nfmt.Println()\n```\n\n" +
+		"- [x] done\n- item with `code` and [a link](https://example.com)\n\n" +
+		"| a | b |\n|:-|-:|\n| 1 | 2 |\n\n" +
+		"![img](data:image/png;base64,iVBORw0KGgo=) ~~old~~ **bold**\n"
+	out := string(markdownToHTML(input))
+	for _, want := range []string{
+		`<code class="language-go">`,
+		`<input checked="" disabled="" type="checkbox">`,
+		`<code>code</code>`,
+		`<a href="https://example.com"`,
+		`<th style="text-align: left">`,
+		`<td style="text-align: right">`,
+		`<img src="data:image/png;base64,iVBORw0KGgo=" alt="img">`,
+		`<del>old</del>`,
+		`<strong>bold</strong>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in %q", want, out)
+		}
+	}
+
+	var raw bytes.Buffer
+	if err := markdownEngine.Convert([]byte(input), &raw); err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if strings.Count(raw.String(), "<") != strings.Count(out, "<") {
+		t.Fatalf("sanitizer dropped legitimate tags:\n%s\nvs\n%s", raw.String(), out)
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// serverMetrics holds the counters behind /metrics. A nil *serverMetrics
+// (metrics disabled) ignores every observation.
+type serverMetrics struct {
+	searches      atomic.Int64
+	searchNanos   atomic.Int64
+	sharesCreated atomic.Int64
+}
+
+// EnableMetrics serves Prometheus text-format metrics at /metrics.
+func (s *Server) EnableMetrics() {
+	s.metrics = &serverMetrics{}
+}
+
+func (m *serverMetrics) observeSearch(took time.Duration) {
+	if m == nil {
+		return
+	}
+	m.searches.Add(1)
+	m.searchNanos.Add(int64(took))
+}
+
+func (m *serverMetrics) observeShare() {
+	if m == nil {
+		return
+	}
+	m.sharesCreated.Add(1)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	var b strings.Builder
+	metric := func(name, kind, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatMetric(value))
+	}
+	metric("codex_manager_sessions_indexed", "gauge", "Session files in the sessions index.", float64(s.idx.FileCount()))
+	metric("codex_manager_scan_duration_seconds", "gauge", "Duration of the last successful sessions scan.", s.idx.LastScanDuration().Seconds())
+	if updated := s.idx.LastUpdated(); !updated.IsZero() {
+		metric("codex_manager_last_scan_timestamp_seconds", "gauge", "Unix time of the last successful sessions scan.", float64(updated.UnixMilli())/1000)
+	}
+	if s.search != nil {
+		files, entries := s.search.Counts()
+		metric("codex_manager_search_index_files", "gauge", "Session files in the search index.", float64(files))
+		metric("codex_manager_search_index_entries", "gauge", "Searchable entries in the search index.", float64(entries))
+	}
+	// The summary's _count is the number of searches run, _sum their total time.
+	b.WriteString("# HELP codex_manager_search_duration_seconds Time spent running /search queries.\n# TYPE codex_manager_search_duration_seconds summary\n")
+	fmt.Fprintf(&b, "codex_manager_search_duration_seconds_sum %s\ncodex_manager_search_duration_seconds_count %d\n",
+		formatMetric(time.Duration(s.metrics.searchNanos.Load()).Seconds()), s.metrics.searches.Load())
+	metric("codex_manager_shares_created_total", "counter", "Shares published locally or to htmlbucket.", float64(s.metrics.sharesCreated.Load()))
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/search"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "b.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec
+	}
+	if rec := get(); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while metrics are disabled, got %d", rec.Code)
+	}
+	server.EnableMetrics()
+
+	for _, target := range []string{"/search?query=hello", "/search?query=h"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/share/"+datePath+"/"+fileName, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("share: got %d", rec.Code)
+	}
+
+	rec = get()
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("expected the text exposition format, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE codex_manager_sessions_indexed gauge\ncodex_manager_sessions_indexed 2\n",
+		"codex_manager_search_index_files 2\n",
+		"# TYPE codex_manager_search_duration_seconds summary\n",
+		// The one-character query is below -search-min-query and never runs.
+		"codex_manager_search_duration_seconds_count 1\n",
+		"# TYPE codex_manager_shares_created_total counter\ncodex_manager_shares_created_total 1\n",
+		"codex_manager_last_scan_timestamp_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package web
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPIDocument describes the JSON endpoints. It is maintained by hand;
+// TestOpenAPIDocumentMatchesRoutes fails when a documented path stops routing.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(openAPIDocument)
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/search"
+)
+
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("expected OpenAPI 3, got %q", doc.OpenAPI)
+	}
+
+	// Every documented operation must reach a handler rather than the fallback 404.
+	samples := strings.NewReplacer("{year}", "2026", "{month}", "01", "{day}", "09", "{file}", "a.jsonl", "{id}", "a.jsonl")
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			target := samples.Replace(path)
+			if path == "/search" {
+				target += "?query=hello"
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(strings.ToUpper(method), target, nil))
+			if rec.Code == http.StatusNotFound {
+				t.Fatalf("%s %s: documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	refs := regexp.MustCompile(`"\$ref": "#/components/(\w+)/(\w+)"`).FindAllStringSubmatch(string(openAPIDocument), -1)
+	var spec struct {
+		Components map[string]map[string]json.RawMessage `json:"components"`
+	}
+	if err := json.Unmarshal(openAPIDocument, &spec); err != nil {
+		t.Fatalf("decode components: %v", err)
+	}
+	for _, ref := range refs {
+		if _, ok := spec.Components[ref[1]][ref[2]]; !ok {
+			t.Fatalf("dangling $ref #/components/%s/%s", ref[1], ref[2])
+		}
+	}
+}
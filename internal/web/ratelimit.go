@@ -0,0 +1,121 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiterMaxClients bounds the bucket map; full (idle) buckets are pruned first.
+const rateLimiterMaxClients = 1024
+
+// rateLimiter is a per-client token bucket: each client may burst up to
+// perMinute requests and regains one token every minute/perMinute.
+type rateLimiter struct {
+	mu      sync.Mutex
+	burst   float64
+	refill  float64 // tokens per second
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		burst:   float64(perMinute),
+		refill:  float64(perMinute) / 60,
+		buckets: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+}
+
+// allow takes a token for client, reporting false when its bucket is empty.
+func (l *rateLimiter) allow(client string) bool {
+	return l.allowN(client, 1)
+}
+
+// capacity is the most tokens a bucket ever holds, so allowN with a larger
+// n can never succeed.
+func (l *rateLimiter) capacity() int {
+	return int(l.burst)
+}
+
+// allowN takes n tokens for client at once, or none when fewer are left.
+func (l *rateLimiter) allowN(client string, n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	bucket, ok := l.buckets[client]
+	if !ok {
+		if len(l.buckets) >= rateLimiterMaxClients {
+			l.prune(now)
+		}
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.refill
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	bucket.last = now
+	if bucket.tokens < float64(n) {
+		return false
+	}
+	bucket.tokens -= float64(n)
+	return true
+}
+
+// prune drops buckets that have refilled completely, since a fresh bucket
+// behaves the same. If none qualify the map is reset rather than grown.
+func (l *rateLimiter) prune(now time.Time) {
+	for client, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.refill >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+	if len(l.buckets) >= rateLimiterMaxClients {
+		l.buckets = map[string]*tokenBucket{}
+	}
+}
+
+// shareSlotWait is how long a share waits for a free render slot.
+var shareSlotWait = 5 * time.Second
+
+// acquireShareSlot takes a render slot, waiting up to shareSlotWait. It
+// reports false when the wait runs out or the client goes away; otherwise
+// release must be called once the share is published.
+func (s *Server) acquireShareSlot(r *http.Request) (release func(), ok bool) {
+	if s.shareSlots == nil {
+		return func() {}, true
+	}
+	timer := time.NewTimer(shareSlotWait)
+	defer timer.Stop()
+	select {
+	case s.shareSlots <- struct{}{}:
+		return func() { <-s.shareSlots }, true
+	case <-timer.C:
+		return nil, false
+	case <-r.Context().Done():
+		return nil, false
+	}
+}
+
+func writeShareBusy(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "5")
+	writeJSONError(w, http.StatusServiceUnavailable, "too many shares rendering; try again shortly")
+}
+
+// clientIP keys rate limiting on the connection's remote address. Forwarded
+// headers are ignored since any client can set them.
+func clientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+const (
+	defaultRecentLimit = 20
+	maxRecentLimit     = 200
+)
+
+type recentSession struct {
+	Date    string    `json:"date"`
+	Path    string    `json:"path"`
+	File    string    `json:"file"`
+	Cwd     string    `json:"cwd"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// recentSessions returns the limit most recently modified sessions across all
+// cwds. Each date's files are sorted newest first, so only the first limit of
+// every date can make the cut; older dates still count because a resumed
+// session keeps the date it was started on.
+func (s *Server) recentSessions(limit int) []recentSession {
+	var files []sessions.SessionFile
+	for _, date := range s.idx.Dates() {
+		dayFiles := s.idx.SessionsByDate(date)
+		files = append(files, dayFiles[:min(len(dayFiles), limit)]...)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime.After(files[j].ModTime)
+	})
+	files = files[:min(len(files), limit)]
+
+	out := make([]recentSession, 0, len(files))
+	for _, file := range files {
+		out = append(out, recentSession{
+			Date:    file.Date.String(),
+			Path:    file.Date.Path(),
+			File:    file.Name,
+			Cwd:     displayCwd(sessions.CwdForFile(file)),
+			ModTime: file.ModTime.In(s.location),
+		})
+	}
+	return out
+}
+
+func (s *Server) handleRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	limit := intParam(r, "limit")
+	if limit == 0 {
+		limit = defaultRecentLimit
+	}
+	limit = min(limit, maxRecentLimit)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.recentSessions(limit))
+}
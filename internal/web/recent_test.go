@@ -0,0 +1,48 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleRecent(t *testing.T) {
+	sessionsDir := t.TempDir()
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/one", base.Add(-3*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/two", base.Add(-time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/08", "c.jsonl", "", base.Add(-2*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/01", "resumed.jsonl", "/one", base)
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recent?limit=3", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var got []recentSession
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	var names []string
+	for _, entry := range got {
+		names = append(names, entry.File)
+	}
+	if len(names) != 3 || names[0] != "resumed.jsonl" || names[1] != "b.jsonl" || names[2] != "c.jsonl" {
+		t.Fatalf("expected newest three by modtime, got %v", names)
+	}
+	if got[1].Date != "2026-01-09" || got[1].Path != "2026/01/09" || got[1].Cwd != "/two" {
+		t.Fatalf("unexpected entry: %+v", got[1])
+	}
+	if got[2].Cwd != "" {
+		t.Fatalf("expected empty cwd for a session without one, got %q", got[2].Cwd)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recent", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || len(got) != 4 {
+		t.Fatalf("expected all 4 sessions with the default limit, got %d (%v)", len(got), err)
+	}
+}
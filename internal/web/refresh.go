@@ -0,0 +1,71 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrRefreshInProgress is returned by Rescan when another rescan is running.
+	ErrRefreshInProgress = errors.New("refresh already in progress")
+	// ErrSearchReindex marks a rescan whose sessions index refreshed but whose
+	// search rebuild hit an error (usually one unreadable file).
+	ErrSearchReindex = errors.New("search reindex failed")
+)
+
+type refreshResponse struct {
+	LastUpdated string `json:"lastUpdated"`
+	Files       int    `json:"files"`
+	Added       int    `json:"added"`
+	Warning     string `json:"warning,omitempty"`
+}
+
+// Rescan refreshes the sessions index, rebuilds the search index, and notifies
+// /events clients of new sessions. Overlapping calls return
+// ErrRefreshInProgress instead of stacking scans.
+func (s *Server) Rescan() ([]string, error) {
+	if !s.refreshMu.TryLock() {
+		return nil, ErrRefreshInProgress
+	}
+	defer s.refreshMu.Unlock()
+
+	added, err := s.idx.RefreshChanges()
+	if err != nil {
+		return nil, err
+	}
+	var searchErr error
+	if s.search != nil {
+		if err := s.search.RefreshFrom(s.idx); err != nil {
+			searchErr = fmt.Errorf("%w: %v", ErrSearchReindex, err)
+		}
+	}
+	s.PublishNewSessions(added)
+	return added, searchErr
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	added, err := s.Rescan()
+	if errors.Is(err, ErrRefreshInProgress) {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	response := refreshResponse{}
+	if errors.Is(err, ErrSearchReindex) {
+		response.Warning = err.Error()
+	} else if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "refresh failed")
+		return
+	}
+	response.LastUpdated = s.idx.LastUpdated().Format(time.RFC3339)
+	response.Files = s.idx.FileCount()
+	response.Added = len(added)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
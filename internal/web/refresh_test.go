@@ -0,0 +1,58 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codex-manager/internal/search"
+)
+
+func TestHandleRefresh(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "b.jsonl", "/proj", time.Now())
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/refresh", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var response refreshResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if response.Files != 2 || response.Added != 1 {
+		t.Fatalf("expected 2 files with 1 added, got %+v", response)
+	}
+	if _, err := time.Parse(time.RFC3339, response.LastUpdated); err != nil {
+		t.Fatalf("expected RFC3339 lastUpdated, got %q", response.LastUpdated)
+	}
+	if results := server.search.Search("Hello", 10); len(results) != 2 {
+		t.Fatalf("expected search to include the new session, got %d results", len(results))
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/refresh", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for GET, got %d", rec.Code)
+	}
+}
+
+func TestHandleRefreshRejectsOverlap(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	server.refreshMu.Lock()
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/refresh", nil))
+	server.refreshMu.Unlock()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 while a refresh is running, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"codex-manager/internal/sessions"
+)
+
+// resumeInfo is the data behind the "Copy resume command" snippet, for tools
+// that would rather run codex themselves than parse the shell text.
+type resumeInfo struct {
+	Cwd     string `json:"cwd,omitempty"`
+	ID      string `json:"id"`
+	Command string `json:"command"`
+}
+
+// metaResponse is the /api/meta body: the SessionMeta fields plus resume.
+type metaResponse struct {
+	*sessions.SessionMeta
+	Resume *resumeInfo `json:"resume,omitempty"`
+}
+
+// resumeFor returns nil when meta has no session id to resume. Values from the
+// file are untrusted: an id with control characters yields nil, one with shell
+// metacharacters is quoted, and a cwd with control characters (say, an
+// embedded newline) is dropped so the snippet stays one cd plus one command.
+func resumeFor(meta *sessions.SessionMeta) *resumeInfo {
+	if meta == nil || meta.ID == "" || hasControlChars(meta.ID) {
+		return nil
+	}
+	id := meta.ID
+	if !isShellWord(id) {
+		id = shellQuote(id)
+	}
+	info := &resumeInfo{ID: meta.ID, Command: fmt.Sprintf("codex resume %s", id)}
+	if meta.Cwd != "" && !hasControlChars(meta.Cwd) {
+		info.Cwd = meta.Cwd
+		info.Command = fmt.Sprintf("cd %s\n%s", shellQuote(meta.Cwd), info.Command)
+	}
+	return info
+}
+
+func hasControlChars(value string) bool {
+	return strings.IndexFunc(value, unicode.IsControl) >= 0
+}
+
+// isShellWord reports whether value can be pasted into a shell unquoted.
+func isShellWord(value string) bool {
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func buildResumeCommand(meta *sessions.SessionMeta) string {
+	if info := resumeFor(meta); info != nil {
+		return info.Command
+	}
+	return ""
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request, resumePath string) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.lookupFilePath(resumePath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	meta, err := sessions.ParseSessionMeta(file.Path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read session metadata")
+		return
+	}
+	info := resumeFor(meta)
+	if info == nil {
+		writeJSONError(w, http.StatusNotFound, "session has no id to resume")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(info)
+}
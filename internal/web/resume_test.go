@@ -0,0 +1,82 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+func TestHandleResume(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/my proj", time.Now())
+	noID := filepath.Join(sessionsDir, "2026", "01", "09", "b.jsonl")
+	if err := os.WriteFile(noID, []byte("{\"type\":\"session_meta\",\"payload\":{\"cwd\":\"/proj\"}}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/resume/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var info resumeInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := resumeInfo{Cwd: "/my proj", ID: "a.jsonl", Command: "cd '/my proj'\ncodex resume a.jsonl"}
+	if info != want {
+		t.Fatalf("expected %+v, got %+v", want, info)
+	}
+
+	for _, target := range []string{"/api/resume/2026/01/09/b.jsonl", "/api/resume/2026/01/09/missing.jsonl", "/api/resume/2026/01/09"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestResumeForWithoutCwd(t *testing.T) {
+	if info := resumeFor(nil); info != nil {
+		t.Fatalf("expected nil for nil meta, got %+v", info)
+	}
+	info := resumeFor(&sessions.SessionMeta{ID: "abc"})
+	if info == nil || info.Command != "codex resume abc" || info.Cwd != "" {
+		t.Fatalf("unexpected resume info: %+v", info)
+	}
+}
+
+func TestResumeForSanitizesUntrustedValues(t *testing.T) {
+	info := resumeFor(&sessions.SessionMeta{ID: "abc", Cwd: "/tmp/x\nrm -rf ~"})
+	if info == nil || info.Command != "codex resume abc" || info.Cwd != "" {
+		t.Fatalf("expected a cwd with a newline to be dropped, got %+v", info)
+	}
+	if strings.Contains(info.Command, "rm") {
+		t.Fatalf("crafted cwd leaked into the command: %q", info.Command)
+	}
+
+	info = resumeFor(&sessions.SessionMeta{ID: "abc; rm -rf ~", Cwd: "/it's"})
+	want := "cd '/it'\"'\"'s'\ncodex resume 'abc; rm -rf ~'"
+	if info == nil || info.Command != want {
+		t.Fatalf("expected quoted id and cwd, got %+v", info)
+	}
+	if strings.Count(info.Command, "\n") != 1 {
+		t.Fatalf("expected exactly two lines, got %q", info.Command)
+	}
+
+	if info := resumeFor(&sessions.SessionMeta{ID: "abc\r\ndef"}); info != nil {
+		t.Fatalf("expected no resume info for an id with control characters, got %+v", info)
+	}
+	if info := resumeFor(&sessions.SessionMeta{ID: "abc", Cwd: "/tmp/\x1b[2J"}); info == nil || info.Cwd != "" {
+		t.Fatalf("expected an escape sequence in the cwd to be dropped, got %+v", info)
+	}
+}
@@ -10,21 +10,26 @@ import (
 	"fmt"
 	"html"
 	"html/template"
+	"io"
 	"math"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"codex-manager/internal/render"
 	"codex-manager/internal/search"
 	"codex-manager/internal/sessions"
 
+	"github.com/microcosm-cc/bluemonday"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/extension"
 )
@@ -45,8 +50,49 @@ type Server struct {
 	useTailscale  bool
 	tailscaleHost string
 	htmlBucket    htmlBucketUploader
+	events        *eventHub
+	maxParseSize  int64
+	edgeItems     int
+	editor        *editorCommand
+	archiveDir    string
+	location      *time.Location
+	prices        sessions.PriceTable
+	usage         *usageCache
+	// etagSalt changes per process so cached pages are revalidated after a
+	// restart, which may bring new templates or flags.
+	etagSalt       string
+	shareLimit     *rateLimiter
+	searchLimit    int
+	searchMaxLimit int
+	searchMinQuery int
+	// defaultView and defaultHeat apply when / is opened without a query.
+	defaultView string
+	defaultHeat string
+	keyBindings map[string]string
+	hideEmpty   bool
+	// refreshMu keeps periodic and on-demand rescans from overlapping.
+	refreshMu sync.Mutex
+	// singlePortShares serves shares under /shared/ (see EnableSinglePortShares).
+	singlePortShares bool
+	shareCSP         string
+	// themeVars overrides the theme's CSS custom properties (see SetThemeColors).
+	themeVars template.CSS
+	// readOnly rejects mutating requests (see SetReadOnly).
+	readOnly bool
+	// shareMode is the permission of local share files (see SetShareMode).
+	shareMode os.FileMode
+	// metrics is nil unless EnableMetrics was called.
+	metrics *serverMetrics
+	// lastSeen is nil unless EnableStateDir was called.
+	lastSeen *lastSeenStore
+	// shareSlots bounds concurrent share renders; nil means unlimited (see
+	// SetShareConcurrency).
+	shareSlots chan struct{}
 }
 
+// errSessionTooLarge is returned by buildSessionView when a file exceeds maxParseSize.
+var errSessionTooLarge = errors.New("session too large to render")
+
 // NewServer wires up the HTTP server.
 func NewServer(idx *sessions.Index, searchIdx *search.Index, renderer *render.Renderer, sessionsDir, shareDir, shareAddr string, theme int) *Server {
 	return &Server{
@@ -57,6 +103,71 @@ func NewServer(idx *sessions.Index, searchIdx *search.Index, renderer *render.Re
 		shareDir:    shareDir,
 		shareAddr:   shareAddr,
 		themeClass:  themeClass(theme),
+		events:      newEventHub(),
+		location:    time.Local,
+		usage:       newUsageCache(),
+		etagSalt:    strconv.FormatInt(time.Now().UnixNano(), 36),
+
+		searchLimit:    defaultSearchLimit,
+		searchMaxLimit: defaultSearchMaxLimit,
+		searchMinQuery: defaultSearchMinQuery,
+		defaultView:    "dir",
+		defaultHeat:    "1h",
+		shareMode:      defaultShareMode,
+	}
+}
+
+// SetIndexDefaults sets the view ("date" or "dir") and heat mode the index
+// page uses when it is opened without query parameters. Heat accepts the
+// same values as the heat query parameter.
+func (s *Server) SetIndexDefaults(view, heat string) error {
+	if view != "date" && view != "dir" {
+		return fmt.Errorf("invalid default view %q (want date or dir)", view)
+	}
+	mode, _, ok := lookupHeatMode(heat)
+	if !ok {
+		return fmt.Errorf("invalid default heat %q (want today, 1h, 7d, a duration like 24h, or <n>d)", heat)
+	}
+	s.defaultView = view
+	s.defaultHeat = mode
+	return nil
+}
+
+// Search defaults used until SetSearchLimits is called.
+const (
+	defaultSearchLimit    = 50
+	defaultSearchMaxLimit = 200
+	defaultSearchMinQuery = 2
+)
+
+// SetSearchLimits sets the /search result count used when no limit is given,
+// the cap on requested limits, and the shortest query that runs a search.
+// Non-positive values keep the current setting.
+func (s *Server) SetSearchLimits(defaultCount, maxCount, minQuery int) {
+	if maxCount > 0 {
+		s.searchMaxLimit = maxCount
+	}
+	if defaultCount > 0 {
+		s.searchLimit = defaultCount
+	}
+	s.searchLimit = min(s.searchLimit, s.searchMaxLimit)
+	if minQuery > 0 {
+		s.searchMinQuery = minQuery
+	}
+	if s.search != nil {
+		s.search.SetLimits(s.searchLimit, s.searchMaxLimit)
+	}
+}
+
+// SetHideEmpty leaves sessions without any conversation out of day listings.
+func (s *Server) SetHideEmpty(hide bool) {
+	s.hideEmpty = hide
+}
+
+// SetLocation sets the timezone used for displayed times.
+func (s *Server) SetLocation(loc *time.Location) {
+	if loc != nil {
+		s.location = loc
 	}
 }
 
@@ -66,13 +177,104 @@ func (s *Server) EnableTailscale(host string) {
 	s.tailscaleHost = strings.TrimSuffix(host, ".")
 }
 
+// SetMaxParseSize refuses to render session files larger than size bytes; 0 disables the limit.
+func (s *Server) SetMaxParseSize(size int64) {
+	s.maxParseSize = size
+}
+
+// SetTruncateItems makes the session page render only the first and last n
+// items of longer sessions unless ?full=1 is given; 0 renders everything.
+func (s *Server) SetTruncateItems(n int) {
+	s.edgeItems = n
+}
+
 // EnableHTMLBucket configures htmlbucket as the active share backend.
 func (s *Server) EnableHTMLBucket(client htmlBucketUploader) {
 	s.htmlBucket = client
 }
 
+// defaultShareMode keeps local shares readable only by the user running the
+// server, which is all the built-in share server needs.
+const defaultShareMode os.FileMode = 0o600
+
+// SetShareMode sets the permission of new local share files. The share dir
+// gets the same bits plus search (x) wherever read is granted, and is
+// re-chmodded on each share when mode differs from the default, so an
+// existing 0700 dir is opened up for another web server too.
+func (s *Server) SetShareMode(mode os.FileMode) {
+	s.shareMode = mode.Perm()
+}
+
+func shareDirMode(mode os.FileMode) os.FileMode {
+	return mode | (mode&0o444)>>2
+}
+
+// SetShareRateLimit caps share creation at perMinute requests per client IP
+// (with the same burst); 0 disables the limit.
+func (s *Server) SetShareRateLimit(perMinute int) {
+	if perMinute <= 0 {
+		s.shareLimit = nil
+		return
+	}
+	s.shareLimit = newRateLimiter(perMinute)
+}
+
+// SetShareConcurrency caps how many shares render and publish at once; a
+// share arriving while every slot is busy waits up to shareSlotWait, then
+// gets 503. 0 disables the limit.
+func (s *Server) SetShareConcurrency(n int) {
+	if n <= 0 {
+		s.shareSlots = nil
+		return
+	}
+	s.shareSlots = make(chan struct{}, n)
+}
+
+// SetReadOnly turns off every action that changes state (sharing, archiving,
+// revoking shares, opening an editor); those requests get 403 and their
+// buttons are hidden. Browsing, search, and rescans keep working.
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// isMutatingRequest reports whether a request changes state. Every
+// non-GET/HEAD route does except POST /api/refresh, which only rescans.
+func isMutatingRequest(r *http.Request, pathValue string) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return !(r.Method == http.MethodPost && pathValue == "api/refresh")
+}
+
+// isCrossSiteRequest reports whether a browser sent r on behalf of another
+// site, going by Sec-Fetch-Site and Origin. Loopback checks cannot catch this,
+// since the victim's own browser makes the request. Clients that send neither
+// header (curl, scripts) are not browsers and pass.
+func isCrossSiteRequest(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	parsed, err := url.Parse(origin)
+	return err != nil || !strings.EqualFold(parsed.Host, r.Host)
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	pathValue := strings.Trim(r.URL.Path, "/")
+	if s.readOnly && isMutatingRequest(r, pathValue) {
+		writeJSONError(w, http.StatusForbidden, "server is in read-only mode")
+		return
+	}
+	if isMutatingRequest(r, pathValue) && isCrossSiteRequest(r) {
+		writeJSONError(w, http.StatusForbidden, "cross-site request rejected")
+		return
+	}
 	if pathValue == "" {
 		s.handleIndex(w, r)
 		return
@@ -85,6 +287,102 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleSearch(w, r)
 		return
 	}
+	if pathValue == "events" {
+		s.handleEvents(w, r)
+		return
+	}
+	if _, ok := appAssets[pathValue]; ok {
+		s.handleAppAsset(w, r, pathValue)
+		return
+	}
+	if pathValue == "metrics" {
+		s.handleMetrics(w, r)
+		return
+	}
+	if pathValue == "api/openapi.json" {
+		s.handleOpenAPI(w, r)
+		return
+	}
+	if pathValue == "api/ui-config" {
+		s.handleUIConfig(w, r)
+		return
+	}
+	if pathValue == "api/recent" {
+		s.handleRecent(w, r)
+		return
+	}
+	if pathValue == "api/tools" {
+		s.handleTools(w, r)
+		return
+	}
+	if pathValue == "api/cwds" {
+		s.handleCwds(w, r)
+		return
+	}
+	if pathValue == "api/cwd-activity" {
+		s.handleCwdActivity(w, r)
+		return
+	}
+	if pathValue == "api/refresh" {
+		s.handleRefresh(w, r)
+		return
+	}
+	if pathValue == "compare" {
+		s.handleCompare(w, r)
+		return
+	}
+	if pathValue == "latest" {
+		s.handleLatest(w, r)
+		return
+	}
+	if pathValue == "today" {
+		s.handleToday(w, r)
+		return
+	}
+	if pathValue == "usage" {
+		s.handleUsage(w, r)
+		return
+	}
+	if pathValue == "shares" {
+		s.handleShares(w, r)
+		return
+	}
+	if pathValue == "shares/download.zip" {
+		s.handleSharesZip(w, r)
+		return
+	}
+	if strings.HasPrefix(pathValue, "shares/revoke/") {
+		s.handleRevokeShare(w, r, strings.TrimPrefix(pathValue, "shares/revoke/"))
+		return
+	}
+	if pathValue == "archive" || strings.HasPrefix(pathValue, "archive/") {
+		s.handleArchive(w, r, strings.TrimPrefix(strings.TrimPrefix(pathValue, "archive"), "/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "id/") {
+		s.handleSessionByID(w, r, strings.TrimPrefix(pathValue, "id/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "api/meta/") {
+		s.handleMeta(w, r, strings.TrimPrefix(pathValue, "api/meta/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "api/instructions/") {
+		s.handleInstructions(w, r, strings.TrimPrefix(pathValue, "api/instructions/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "api/resume/") {
+		s.handleResume(w, r, strings.TrimPrefix(pathValue, "api/resume/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "api/session-stats/") {
+		s.handleSessionStats(w, r, strings.TrimPrefix(pathValue, "api/session-stats/"))
+		return
+	}
+	if strings.HasPrefix(pathValue, "shared/") {
+		s.handleShared(w, r, strings.TrimPrefix(pathValue, "shared/"))
+		return
+	}
 	if strings.HasPrefix(pathValue, "raw/") {
 		s.handleRaw(w, r, strings.TrimPrefix(pathValue, "raw/"))
 		return
@@ -95,6 +393,14 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.handleShare(w, r, parts[1:])
 		return
 	}
+	if len(parts) == 4 && r.Method == http.MethodPost && parts[0] == "share-day" {
+		s.handleShareDay(w, r, parts[1:])
+		return
+	}
+	if len(parts) == 5 && r.Method == http.MethodPost && parts[0] == "open" {
+		s.handleOpen(w, r, parts[1:])
+		return
+	}
 	if len(parts) == 3 {
 		s.handleDay(w, r, parts)
 		return
@@ -119,6 +425,11 @@ type dirView struct {
 	Count       int
 	RecentCount int
 	HeatColor   template.CSS
+	GitRepo     string
+	GitBranch   string
+	// Unread is set when a session changed after the directory page was
+	// last opened (requires -state-dir).
+	Unread bool
 }
 
 type sessionView struct {
@@ -127,6 +438,31 @@ type sessionView struct {
 	ModTime       string
 	ResumeCommand string
 	Cwd           string
+	GitRepo       string
+	GitBranch     string
+	Summary       string
+	CliVersion    string
+	// OutdatedCli is set when CliVersion is older than the newest indexed version.
+	OutdatedCli bool
+	Empty       bool
+	Kind        sessions.SessionKind
+	// Shadowed rows share their date and name with a newer file, which is
+	// the one the session routes open; they are listed but not linked.
+	Shadowed bool
+}
+
+// versionView and kindView are day page filter tabs; Query is the day's
+// query string with that tab selected and the other filters kept.
+type versionView struct {
+	Value string
+	Count int
+	Query template.URL
+}
+
+type kindView struct {
+	Value sessions.SessionKind
+	Count int
+	Query template.URL
 }
 
 type indexView struct {
@@ -137,22 +473,56 @@ type indexView struct {
 	View        string
 	HeatMode    string
 	ThemeClass  string
+	ThemeVars   template.CSS
+	// ArchiveEnabled shows the Archive tab when -archive-dir is set.
+	ArchiveEnabled bool
+	// SearchMinQuery mirrors the server's minimum query length for the search box.
+	SearchMinQuery int
+	// SessionsDirMissing replaces the empty state with a not-found notice
+	// until a rescan finds the sessions directory.
+	SessionsDirMissing bool
 }
 
 type dayView struct {
-	Date             dateView
-	Sessions         []sessionView
-	Dirs             []dirView
+	Date     dateView
+	Sessions []sessionView
+	Dirs     []dayDirView
+	// SelectedCwds holds every cwd filter (the union is shown); SelectedCwd is
+	// set only when exactly one is selected. CwdQuery re-encodes them for links.
+	SelectedCwds     []string
 	SelectedCwd      string
+	CwdQuery         template.URL
 	SelectedCwdLabel string
+	Versions         []versionView
+	SelectedVersion  string
 	View             string
 	ThemeClass       string
+	ThemeVars        template.CSS
+	EditorEnabled    bool
+	ArchiveEnabled   bool
+	// HiddenEmpty counts sessions left out by -hide-empty.
+	HiddenEmpty int
+	// AllVersions and AllKinds are the queries of the "All" tabs.
+	AllVersions  template.URL
+	Kinds        []kindView
+	SelectedKind sessions.SessionKind
+	AllKinds     template.URL
+}
+
+// dayDirView is a directory in the day page's filter list; ToggleQuery is
+// the cwd query with this directory added to or removed from the selection.
+type dayDirView struct {
+	dirView
+	Selected    bool
+	ToggleQuery template.URL
 }
 
 type dirPageView struct {
 	Dir        dirView
 	Dates      []dateView
+	Activity   sparklineView
 	ThemeClass string
+	ThemeVars  template.CSS
 }
 
 type sessionPageView struct {
@@ -163,10 +533,56 @@ type sessionPageView struct {
 	AllMarkdown   string
 	ResumeCommand string
 	ThemeClass    string
+	ThemeVars     template.CSS
 	IsJSONL       bool
 	LastUserLine  int
+	Related       []relatedView
+	EditorEnabled bool
+	// Instructions is the rendered system prompt, shown in its own block above the messages.
+	Instructions     template.HTML
+	InstructionsLine int
+	UnparsedLines    int
+	SearchEnabled    bool
+	ArchiveEnabled   bool
+	// PrevSession and NextSession link to the neighbouring rows of the day listing.
+	PrevSession string
+	NextSession string
+	// HiddenItems items were left out before Items[HiddenAt] (see SetTruncateItems).
+	HiddenItems int
+	HiddenAt    int
+	// Shared is set for share renders, which leave out links to this server's assets.
+	Shared bool
+	// ReadOnly hides the Share button (see SetReadOnly).
+	ReadOnly bool
+	// Title is the browser tab title; see sessionTitle.
+	Title string
+	// Cwds lists every directory the session worked in (see Session.Cwds);
+	// the header shows it when there is more than one.
+	Cwds []string
+	// Print renders the stripped ?print=1 layout: no navigation or actions,
+	// a light palette, and every collapsible expanded.
+	Print bool
+	// Desc lists Items newest first (?order=desc); OrderHref links to the
+	// other order, keeping ?full=1. Both are unset for shares.
+	Desc      bool
+	OrderHref string
+	// Chat keeps only message and reasoning items (?view=chat); ChatHref
+	// links to the other view, keeping the other page options.
+	Chat     bool
+	ChatHref string
+}
+
+type relatedView struct {
+	Date    string
+	Path    string
+	Name    string
+	ModTime string
+	Summary string
 }
 
+// relatedLimit caps how many sibling sessions the session page links to.
+const relatedLimit = 5
+
 type itemView struct {
 	Line      int
 	Timestamp string
@@ -179,14 +595,29 @@ type itemView struct {
 	AutoCtx   bool
 	Markdown  string
 	HTML      template.HTML
+	// Role flags let templates and themes style each speaker separately.
+	IsUser      bool
+	IsAssistant bool
+	IsTool      bool
+	// OutputHTML is the fused tool output, shown collapsed under the call.
+	OutputHTML template.HTML
+	OutputLine int
+	// Hidden items (e.g. encrypted reasoning) render as a one-line placeholder.
+	Hidden bool
+	// Aborted items mark an interrupted turn and get an "Aborted" tag.
+	Aborted bool
+	// Unrecognized items come from -lenient and get an "Unrecognized" tag.
+	Unrecognized bool
+	// Repeated is how many identical tool results this item stands for.
+	Repeated int
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	view := r.URL.Query().Get("view")
 	heat := r.URL.Query().Get("heat")
 	if view == "" && r.URL.RawQuery == "" {
-		view = "dir"
-		heat = "1h"
+		view = s.defaultView
+		heat = s.defaultHeat
 	} else if view != "dir" {
 		view = "date"
 	}
@@ -197,6 +628,45 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	_ = s.renderer.Execute(w, "index", indexView)
 }
 
+func (s *Server) handleLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.idx.Latest(normalizeCwdParam(r.URL.Query().Get("cwd")))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/"+file.Date.Path()+"/"+url.PathEscape(file.Name), http.StatusFound)
+}
+
+// handleToday redirects to the day page for the current date in the
+// configured timezone; a day without sessions shows the page's empty state.
+func (s *Server) handleToday(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	now := time.Now().In(s.location)
+	http.Redirect(w, r, "/"+now.Format("2006/01/02")+"/", http.StatusFound)
+}
+
+// handleSessionByID redirects a Codex session id (as shown by codex resume)
+// to the session page of the file whose metadata carries it.
+func (s *Server) handleSessionByID(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.idx.LookupID(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, "/"+file.Date.Path()+"/"+url.PathEscape(file.Name), http.StatusFound)
+}
+
 func (s *Server) handleDir(w http.ResponseWriter, r *http.Request) {
 	cwd := normalizeCwdParam(r.URL.Query().Get("cwd"))
 	if cwd == "" {
@@ -208,8 +678,16 @@ func (s *Server) handleDir(w http.ResponseWriter, r *http.Request) {
 
 	files := s.idx.SessionsByCwd(cwd)
 	counts := make(map[sessions.DateKey]int, len(files))
+	var newest time.Time
 	for _, file := range files {
 		counts[file.Date]++
+		if file.ModTime.After(newest) {
+			newest = file.ModTime
+		}
+	}
+	// Unknown cwds (typos, crawlers) have no sessions and are never stored.
+	if len(files) > 0 {
+		_ = s.lastSeen.markSeen(cwd, time.Now(), newest)
 	}
 
 	dates := s.idx.Dates()
@@ -229,11 +707,14 @@ func (s *Server) handleDir(w http.ResponseWriter, r *http.Request) {
 		Value: cwd,
 		Count: len(files),
 	}
+	dir.GitRepo, dir.GitBranch = s.gitFields(cwd)
 
 	view := dirPageView{
 		Dir:        dir,
 		Dates:      dateViews,
+		Activity:   buildSparkline(s.cwdActivity(cwd, defaultActivityDays, time.Now())),
 		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -246,24 +727,56 @@ func (s *Server) handleDay(w http.ResponseWriter, r *http.Request, parts []strin
 		http.NotFound(w, r)
 		return
 	}
-	selectedCwd := normalizeCwdParam(r.URL.Query().Get("cwd"))
+	cwds := selectedCwds(r.URL.Query()["cwd"])
+	cwdSet := make(map[string]bool, len(cwds))
+	for _, cwd := range cwds {
+		cwdSet[cwd] = true
+	}
+	// SelectedCwd keeps the single-directory links (dir page, label) working.
+	selectedCwd := ""
+	if len(cwds) == 1 {
+		selectedCwd = cwds[0]
+	}
+	selectedVersion := strings.TrimSpace(r.URL.Query().Get("version"))
+	selectedKind, _ := sessions.ParseSessionKind(strings.TrimSpace(r.URL.Query().Get("type")))
 	viewMode := strings.TrimSpace(r.URL.Query().Get("view"))
 	if viewMode != "dir" {
 		viewMode = "sessions"
 	}
 
 	files := s.idx.SessionsByDate(date)
-	dirViews := buildDirViewsFromFiles(files)
+	dirViews := s.withGit(buildDirViewsFromFiles(files))
+	dayDirs := make([]dayDirView, 0, len(dirViews))
+	for _, dir := range dirViews {
+		dayDirs = append(dayDirs, dayDirView{
+			dirView:     dir,
+			Selected:    cwdSet[dir.Value],
+			ToggleQuery: cwdQuery(toggleCwd(cwds, dir.Value)),
+		})
+	}
 
 	filtered := files
-	if selectedCwd != "" {
+	hiddenEmpty := 0
+	if len(cwds) > 0 || selectedVersion != "" || selectedKind != "" || s.hideEmpty {
 		filtered = make([]sessions.SessionFile, 0, len(files))
 		for _, file := range files {
-			if sessions.CwdForFile(file) == selectedCwd {
-				filtered = append(filtered, file)
+			if s.hideEmpty && file.Empty {
+				hiddenEmpty++
+				continue
 			}
+			if len(cwds) > 0 && !cwdSet[sessions.CwdForFile(file)] {
+				continue
+			}
+			if selectedVersion != "" && sessions.CliVersionForFile(file) != selectedVersion {
+				continue
+			}
+			if selectedKind != "" && file.Kind != selectedKind {
+				continue
+			}
+			filtered = append(filtered, file)
 		}
 	}
+	latestCli := s.idx.LatestCliVersion()
 
 	views := make([]sessionView, 0, len(filtered))
 	for _, file := range filtered {
@@ -272,18 +785,30 @@ func (s *Server) handleDay(w http.ResponseWriter, r *http.Request, parts []strin
 		if cwd == sessions.UnknownCwd {
 			cwd = ""
 		}
+		gitRepo, gitBranch := s.gitFields(cwd)
 		views = append(views, sessionView{
 			Name:          file.Name,
 			Size:          formatBytes(file.Size),
-			ModTime:       formatTime(file.ModTime),
+			ModTime:       s.formatTime(file.ModTime),
 			ResumeCommand: resumeCommand,
 			Cwd:           cwd,
+			GitRepo:       gitRepo,
+			GitBranch:     gitBranch,
+			Summary:       file.Summary,
+			CliVersion:    sessions.CliVersionForFile(file),
+			OutdatedCli:   cliOutdated(sessions.CliVersionForFile(file), latestCli),
+			Empty:         file.Empty,
+			Kind:          file.Kind,
+
+			Shadowed: s.idx.Shadowed(file),
 		})
 	}
 
 	selectedLabel := ""
 	if selectedCwd != "" {
 		selectedLabel = dirLabel(selectedCwd)
+	} else if len(cwds) > 1 {
+		selectedLabel = fmt.Sprintf("%d directories", len(cwds))
 	}
 
 	view := dayView{
@@ -293,30 +818,131 @@ func (s *Server) handleDay(w http.ResponseWriter, r *http.Request, parts []strin
 			Count: len(files),
 		},
 		Sessions:         views,
-		Dirs:             dirViews,
+		Dirs:             dayDirs,
 		SelectedCwd:      selectedCwd,
+		SelectedCwds:     cwds,
+		CwdQuery:         cwdQuery(cwds),
 		SelectedCwdLabel: selectedLabel,
+		Versions:         buildVersionViews(files, cwds, selectedKind),
+		SelectedVersion:  selectedVersion,
 		View:             viewMode,
 		ThemeClass:       s.themeClass,
+		ThemeVars:        s.themeVars,
+		EditorEnabled:    s.editor != nil && !s.readOnly,
+		ArchiveEnabled:   s.archiveDir != "" && !s.readOnly,
+		HiddenEmpty:      hiddenEmpty,
+
+		AllVersions:  dayQuery(cwds, "", selectedKind),
+		Kinds:        buildKindViews(files, cwds, selectedVersion),
+		SelectedKind: selectedKind,
+		AllKinds:     dayQuery(cwds, selectedVersion, ""),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	_ = s.renderer.Execute(w, "day", view)
 }
 
+type tooLargeView struct {
+	Date       dateView
+	File       sessionView
+	Limit      string
+	ThemeClass string
+	ThemeVars  template.CSS
+}
+
 func (s *Server) handleSession(w http.ResponseWriter, r *http.Request, parts []string) {
-	view, err := s.buildSessionView(parts)
+	if date, ok := sessions.ParseDate(parts[0], parts[1], parts[2]); ok {
+		if file, ok := s.idx.Lookup(date, parts[3]); ok {
+			w.Header().Set("Cache-Control", "no-cache")
+			if notModified(w, r, s.sessionETag(file), s.sessionModTime(file)) {
+				return
+			}
+		}
+	}
+
+	query := r.URL.Query()
+	printView := query.Get("print") == "1"
+	full := query.Get("full") == "1"
+	desc := query.Get("order") == "desc"
+	chat := query.Get("view") == "chat"
+	edgeItems := s.edgeItems
+	if full || printView {
+		edgeItems = 0
+	}
+	view, err := s.buildSessionView(parts, edgeItems, chat)
+	if errors.Is(err, errSessionTooLarge) {
+		s.renderTooLarge(w, parts)
+		return
+	}
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
+	view.Print = printView
+	if desc {
+		view.reverseItems()
+	}
+	view.OrderHref = sessionPageHref(full, !desc, chat)
+	view.ChatHref = sessionPageHref(full, desc, !chat)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	_ = s.renderer.Execute(w, "session", view)
 }
 
+// reverseItems puts the newest item first. HiddenAt moves to the other
+// side of the gap so the hidden-items marker stays between the same items.
+func (v *sessionPageView) reverseItems() {
+	for i, j := 0, len(v.Items)-1; i < j; i, j = i+1, j-1 {
+		v.Items[i], v.Items[j] = v.Items[j], v.Items[i]
+	}
+	if v.HiddenItems > 0 {
+		v.HiddenAt = len(v.Items) - v.HiddenAt
+	}
+	v.Desc = true
+}
+
+// sessionPageHref is the query of a session page with the given options.
+func sessionPageHref(full, desc, chat bool) string {
+	values := url.Values{}
+	if full {
+		values.Set("full", "1")
+	}
+	if desc {
+		values.Set("order", "desc")
+	}
+	if chat {
+		values.Set("view", "chat")
+	}
+	return "?" + values.Encode()
+}
+
+func (s *Server) renderTooLarge(w http.ResponseWriter, parts []string) {
+	date, _ := sessions.ParseDate(parts[0], parts[1], parts[2])
+	file, _ := s.idx.Lookup(date, parts[3])
+	view := tooLargeView{
+		Date: dateView{
+			Label: date.String(),
+			Path:  date.Path(),
+		},
+		File: sessionView{
+			Name:    file.Name,
+			Size:    formatBytes(file.Size),
+			ModTime: s.formatTime(file.ModTime),
+		},
+		Limit:      formatBytes(s.maxParseSize),
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_ = s.renderer.Execute(w, "toolarge", view)
+}
+
 type searchResponse struct {
 	Query   string          `json:"query"`
+	File    string          `json:"file,omitempty"`
+	Raw     bool            `json:"raw,omitempty"`
+	Offset  int             `json:"offset,omitempty"`
 	Results []search.Result `json:"results"`
 }
 
@@ -331,25 +957,203 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	query := strings.TrimSpace(r.URL.Query().Get("query"))
-	limit := 50
+	limit := s.searchLimit
 	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
 		if parsed, err := strconv.Atoi(rawLimit); err == nil && parsed > 0 {
 			limit = parsed
 		}
 	}
-	if limit > 200 {
-		limit = 200
+	if limit > s.searchMaxLimit {
+		limit = s.searchMaxLimit
+	}
+
+	opts := search.Options{
+		Limit:         limit,
+		PreviewRadius: intParam(r, "previewRadius"),
+		PreviewMax:    intParam(r, "previewMax"),
+		Raw:           r.URL.Query().Get("raw") == "1",
+		Offset:        intParam(r, "offset"),
+	}
+	if rawFile := strings.TrimSpace(r.URL.Query().Get("file")); rawFile != "" {
+		key, ok := parseSessionKey(rawFile)
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "file must be <yyyy-mm-dd>/<name>")
+			return
+		}
+		opts.File = key
 	}
 
 	var results []search.Result
-	if len(query) >= 2 {
-		results = s.search.Search(query, limit)
+	if utf8.RuneCountInString(query) >= s.searchMinQuery {
+		started := time.Now()
+		results = s.search.SearchWithOptions(query, opts)
+		s.metrics.observeSearch(time.Since(started))
 	} else {
 		results = []search.Result{}
 	}
 
+	if r.URL.Query().Get("format") == "jsonl" {
+		writeSearchJSONL(w, results)
+		return
+	}
+	response := searchResponse{Query: query, File: opts.File, Raw: opts.Raw, Offset: opts.Offset, Results: results}
+	if wantsHTML(r) {
+		s.renderSearchPage(w, response, limit)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(searchResponse{Query: query, Results: results})
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// writeSearchJSONL writes one Result per line, flushing after each so a
+// client piping the response can start on the first match right away.
+func writeSearchJSONL(w http.ResponseWriter, results []search.Result) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+type searchPageView struct {
+	Query      string
+	File       string
+	Searched   bool
+	MinQuery   int
+	Raw        bool
+	Results    []searchResultView
+	ThemeClass string
+	ThemeVars  template.CSS
+	// First and Last are the 1-based ranks shown; the hrefs page by limit.
+	First    int
+	Last     int
+	PrevHref string
+	NextHref string
+}
+
+type searchResultView struct {
+	search.Result
+	Segments []previewSegment
+}
+
+type previewSegment struct {
+	Text  string
+	Match bool
+}
+
+// parseSessionKey accepts "yyyy-mm-dd/name" or "yyyy/mm/dd/name" and returns
+// the canonical "yyyy/mm/dd/name" key used by the indexes.
+func parseSessionKey(value string) (string, bool) {
+	value = strings.Trim(value, "/")
+	slash := strings.LastIndex(value, "/")
+	if slash == -1 {
+		return "", false
+	}
+	date, ok := sessions.ParseDateLabel(value[:slash])
+	name := value[slash+1:]
+	if !ok || !safeFilename(name) {
+		return "", false
+	}
+	return date.Path() + "/" + name, true
+}
+
+// wantsHTML reports whether /search should render the results page instead of JSON:
+// an explicit format param wins, otherwise a browser-style Accept header.
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/html") && !strings.Contains(accept, "application/json")
+}
+
+func (s *Server) renderSearchPage(w http.ResponseWriter, response searchResponse, limit int) {
+	view := searchPageView{
+		Query:      response.Query,
+		File:       response.File,
+		Searched:   utf8.RuneCountInString(response.Query) >= s.searchMinQuery,
+		MinQuery:   s.searchMinQuery,
+		Raw:        response.Raw,
+		Results:    make([]searchResultView, 0, len(response.Results)),
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+	}
+	for _, result := range response.Results {
+		view.Results = append(view.Results, searchResultView{
+			Result:   result,
+			Segments: highlightSegments(result.Preview, response.Query),
+		})
+	}
+	view.First = response.Offset + 1
+	view.Last = response.Offset + len(response.Results)
+	pageHref := func(offset int) string {
+		params := url.Values{"format": {"html"}, "query": {response.Query}}
+		if response.File != "" {
+			params.Set("file", response.File)
+		}
+		if response.Raw {
+			params.Set("raw", "1")
+		}
+		if limit != s.searchLimit {
+			params.Set("limit", strconv.Itoa(limit))
+		}
+		if offset > 0 {
+			params.Set("offset", strconv.Itoa(offset))
+		}
+		return "/search?" + params.Encode()
+	}
+	if response.Offset > 0 {
+		view.PrevHref = pageHref(max(response.Offset-limit, 0))
+	}
+	// A full page may be followed by more; an empty next page is cheap.
+	if len(response.Results) == limit {
+		view.NextHref = pageHref(response.Offset + limit)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.renderer.Execute(w, "search", view)
+}
+
+// highlightSegments splits text around case-insensitive occurrences of query.
+func highlightSegments(text, query string) []previewSegment {
+	lowerText := strings.ToLower(text)
+	terms := search.ParseQuery(query).Terms
+	if len(terms) == 0 || len(lowerText) != len(text) {
+		return []previewSegment{{Text: text}}
+	}
+	var segments []previewSegment
+	start := 0
+	for {
+		// Take the earliest occurrence of any term, preferring the longer one on ties.
+		index, length := -1, 0
+		for _, term := range terms {
+			i := strings.Index(lowerText[start:], term)
+			if i != -1 && (index == -1 || i < index || (i == index && len(term) > length)) {
+				index, length = i, len(term)
+			}
+		}
+		if index == -1 {
+			break
+		}
+		if index > 0 {
+			segments = append(segments, previewSegment{Text: text[start : start+index]})
+		}
+		end := start + index + length
+		segments = append(segments, previewSegment{Text: text[start+index : end], Match: true})
+		start = end
+	}
+	if start < len(text) {
+		segments = append(segments, previewSegment{Text: text[start:]})
+	}
+	return segments
 }
 
 func (s *Server) handleShare(w http.ResponseWriter, r *http.Request, parts []string) {
@@ -357,51 +1161,118 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request, parts []str
 		http.NotFound(w, r)
 		return
 	}
+	if s.shareLimit != nil && !s.shareLimit.allow(clientIP(r.RemoteAddr)) {
+		w.Header().Set("Retry-After", "60")
+		writeJSONError(w, http.StatusTooManyRequests, "too many share requests; try again later")
+		return
+	}
+	release, ok := s.acquireShareSlot(r)
+	if !ok {
+		writeShareBusy(w)
+		return
+	}
+	defer release()
 
-	view, err := s.buildSessionView(parts)
+	html, err := s.renderShareHTML(parts)
+	if errors.Is(err, errSessionTooLarge) {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if errors.Is(err, errShareRender) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
 
+	shareURL, _, err := s.publishShare(r, html)
+	if err != nil {
+		writeShareError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"url": shareURL})
+}
+
+// Share failures after rendering; writeShareError maps them to a status.
+var (
+	errShareRender = errors.New("failed to render html")
+	errShareUpload = errors.New("htmlbucket upload failed")
+)
+
+// renderShareHTML renders a session page the way it is shared: complete, and
+// without the actions that only work against this server.
+func (s *Server) renderShareHTML(parts []string) ([]byte, error) {
+	view, err := s.buildSessionView(parts, 0, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared copies are viewed elsewhere; local-only actions make no sense there.
+	view.EditorEnabled = false
+	view.SearchEnabled = false
+	view.ArchiveEnabled = false
+	view.PrevSession, view.NextSession = "", ""
+	view.Shared = true
+
 	var buf bytes.Buffer
 	if err := s.renderer.Execute(&buf, "session", view); err != nil {
-		http.Error(w, fmt.Sprintf("failed to render html: %v", err), http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("%w: %v", errShareRender, err)
 	}
+	return buf.Bytes(), nil
+}
 
+// publishShare uploads html to htmlbucket when it is active, or writes it to
+// the share dir under a fresh token. It returns the share URL and, for local
+// shares, the file name.
+func (s *Server) publishShare(r *http.Request, html []byte) (string, string, error) {
 	if s.htmlBucket != nil {
-		shareURL, err := s.htmlBucket.Upload(r.Context(), buf.String())
+		shareURL, err := s.htmlBucket.Upload(r.Context(), string(html))
 		if err != nil {
-			writeJSONError(w, http.StatusBadGateway, fmt.Sprintf("htmlbucket upload failed: %v", err))
-			return
+			return "", "", fmt.Errorf("%w: %v", errShareUpload, err)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]string{"url": shareURL})
-		return
+		s.metrics.observeShare()
+		return shareURL, "", nil
 	}
 
-	if err := os.MkdirAll(s.shareDir, 0o700); err != nil {
-		http.Error(w, fmt.Sprintf("failed to create share dir: %v", err), http.StatusInternalServerError)
-		return
+	dirMode := shareDirMode(s.shareMode)
+	if err := os.MkdirAll(s.shareDir, dirMode); err != nil {
+		return "", "", fmt.Errorf("failed to create share dir: %v", err)
 	}
-
-	token, err := randomToken(16)
+	if s.shareMode != defaultShareMode {
+		if err := os.Chmod(s.shareDir, dirMode); err != nil {
+			return "", "", fmt.Errorf("failed to set share dir mode: %v", err)
+		}
+	}
+	fileName, err := createShareFile(s.shareDir, html, s.shareMode)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to create share token: %v", err), http.StatusInternalServerError)
-		return
+		return "", "", fmt.Errorf("failed to write share file: %v", err)
 	}
+	s.metrics.observeShare()
+	return s.buildShareURL(r, fileName), fileName, nil
+}
 
-	fileName := formatUUID(token) + ".html"
-	targetFile := filepath.Join(s.shareDir, fileName)
-	if err := os.WriteFile(targetFile, buf.Bytes(), 0o600); err != nil {
-		http.Error(w, fmt.Sprintf("failed to write share file: %v", err), http.StatusInternalServerError)
+func writeShareError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errShareUpload) {
+		writeJSONError(w, http.StatusBadGateway, err.Error())
 		return
 	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
 
-	shareURL := s.buildShareURL(r, fileName)
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"url": shareURL})
+// intParam returns a positive integer query parameter, or 0 when absent or invalid.
+func intParam(r *http.Request, name string) int {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed <= 0 {
+		return 0
+	}
+	return parsed
 }
 
 func writeJSONError(w http.ResponseWriter, status int, message string) {
@@ -410,30 +1281,152 @@ func writeJSONError(w http.ResponseWriter, status int, message string) {
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
-func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request, rawPath string) {
-	parts := strings.Split(strings.Trim(rawPath, "/"), "/")
+// lookupFilePath resolves a "yyyy/mm/dd/file" path, rejecting malformed dates
+// and unsafe filenames.
+func (s *Server) lookupFilePath(filePath string) (sessions.SessionFile, bool) {
+	parts := strings.Split(strings.Trim(filePath, "/"), "/")
 	if len(parts) != 4 {
-		http.NotFound(w, r)
-		return
+		return sessions.SessionFile{}, false
 	}
 	date, ok := sessions.ParseDate(parts[0], parts[1], parts[2])
+	if !ok {
+		return sessions.SessionFile{}, false
+	}
+	if !safeFilename(parts[3]) {
+		return sessions.SessionFile{}, false
+	}
+	return s.idx.Lookup(date, parts[3])
+}
+
+func (s *Server) handleRaw(w http.ResponseWriter, r *http.Request, rawPath string) {
+	file, ok := s.lookupFilePath(rawPath)
 	if !ok {
 		http.NotFound(w, r)
 		return
 	}
-	filename := parts[3]
-	if filename == "" || strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
+	// Only the indexed path is opened, never one built from the request, so the
+	// lookup's date and filename checks are the traversal guard.
+	f, err := os.Open(file.Path)
+	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	file, ok := s.idx.Lookup(date, filename)
-	if !ok {
+	defer f.Close()
+	if info, err := f.Stat(); err != nil || !info.Mode().IsRegular() {
 		http.NotFound(w, r)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
-	http.ServeFile(w, r, file.Path)
+	// ServeContent sets Content-Length from the open file, answers Range and
+	// If-Range (so interrupted downloads resume), and handles If-None-Match
+	// once the ETag header is present.
+	w.Header().Set("ETag", fileETag(file))
+	http.ServeContent(w, r, file.Name, file.ModTime, f)
+}
+
+// handleMeta returns just the session metadata, which ParseSessionMeta reads
+// from the head of the file without parsing the conversation.
+func (s *Server) handleMeta(w http.ResponseWriter, r *http.Request, metaPath string) {
+	file, ok := s.lookupFilePath(metaPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	meta, err := sessions.ParseSessionMeta(file.Path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to read session metadata")
+		return
+	}
+	if meta == nil {
+		meta = &sessions.SessionMeta{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(metaResponse{SessionMeta: meta, Resume: resumeFor(meta)})
+}
+
+// handleInstructions returns the session's instructions (system prompt) as
+// markdown, read from the head of the file like handleMeta; 204 when there are none.
+func (s *Server) handleInstructions(w http.ResponseWriter, r *http.Request, instructionsPath string) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.lookupFilePath(instructionsPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	meta, err := sessions.ParseSessionMeta(file.Path)
+	if err != nil {
+		http.Error(w, "failed to read session metadata", http.StatusInternalServerError)
+		return
+	}
+	if meta == nil || strings.TrimSpace(meta.Instructions) == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	_, _ = io.WriteString(w, meta.Instructions)
+}
+
+// fileETag is a strong validator from the file's size and modtime. Raw
+// downloads bypass Gzip, so the bytes on the wire are the file's, and
+// ServeContent only honours If-Range with a strong ETag.
+func fileETag(file sessions.SessionFile) string {
+	return fmt.Sprintf(`"%x-%x"`, file.Size, file.ModTime.UnixNano())
+}
+
+// sessionETag validates a session page. The page also shows index state
+// (neighbour links, duplicate tags), so a rescan changes it as well.
+func (s *Server) sessionETag(file sessions.SessionFile) string {
+	return fmt.Sprintf(`W/"%x-%x-%x-%s"`, file.Size, file.ModTime.UnixNano(), s.idx.LastUpdated().UnixNano(), s.etagSalt)
+}
+
+// sessionModTime is the Last-Modified of a session page: the later of the
+// file's modtime and the last index refresh, matching sessionETag.
+func (s *Server) sessionModTime(file sessions.SessionFile) time.Time {
+	if updated := s.idx.LastUpdated(); updated.After(file.ModTime) {
+		return updated
+	}
+	return file.ModTime
+}
+
+// notModified sets ETag and Last-Modified and, when the request's validators
+// still match, writes 304 and reports true. If-None-Match takes precedence
+// over If-Modified-Since, as in RFC 9110.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modTime time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !modTime.IsZero() {
+		w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	match := false
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				match = true
+				break
+			}
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modTime.IsZero() {
+		if since, err := http.ParseTime(ims); err == nil && !modTime.Truncate(time.Second).After(since) {
+			match = true
+		}
+	}
+	if !match {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// safeFilename rejects empty names and anything that could escape a date directory.
+func safeFilename(name string) bool {
+	return name != "" && !strings.Contains(name, "..") && !strings.Contains(name, "/") && !strings.Contains(name, "\\")
 }
 
 func formatBytes(size int64) string {
@@ -459,6 +1452,28 @@ func formatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04:05")
 }
 
+// formatTime renders t in the server's display timezone.
+func (s *Server) formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatTime(t.In(s.location))
+}
+
+// formatItemTimestamp converts an RFC3339 envelope timestamp (with or without
+// fractional seconds) to the display timezone; other values pass through.
+func (s *Server) formatItemTimestamp(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return value
+	}
+	ts, err := time.Parse(time.RFC3339Nano, trimmed)
+	if err != nil {
+		return value
+	}
+	return formatTime(ts.In(s.location))
+}
+
 func formatScanTime(t time.Time) string {
 	if t.IsZero() {
 		return "never"
@@ -467,7 +1482,7 @@ func formatScanTime(t time.Time) string {
 }
 
 func (s *Server) buildIndexView(view string, heatMode string) indexView {
-	heatMode = parseHeatMode(heatMode)
+	heatMode, heatWindow := parseHeatMode(heatMode)
 	dates := s.idx.Dates()
 	dateViews := make([]dateView, 0, len(dates))
 	for _, date := range dates {
@@ -483,22 +1498,17 @@ func (s *Server) buildIndexView(view string, heatMode string) indexView {
 	recentMax := 0
 	if view == "dir" {
 		now := time.Now()
-		since := now.AddDate(0, 0, -7)
-		allowFallback := true
-		switch heatMode {
-		case "today":
+		since := now.Add(-heatWindow)
+		allowFallback := heatMode == "7d"
+		if heatMode == "today" {
 			since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-			allowFallback = false
-		case "1h":
-			since = now.Add(-1 * time.Hour)
-			allowFallback = false
 		}
 		recentCounts, recentMax = s.recentCwdCounts(since)
 		if allowFallback && recentMax == 0 {
 			recentCounts, recentMax = s.recentCwdCountsFromLatestDates(7)
 		}
 	}
-	dirViews := buildDirViewsFromCounts(s.idx.CwdCounts(), recentCounts, recentMax, view == "dir")
+	dirViews := s.withUnread(s.withGit(buildDirViewsFromCounts(s.idx.CwdCounts(), recentCounts, recentMax, view == "dir")))
 	lastScan := s.idx.LastUpdated()
 
 	return indexView{
@@ -509,6 +1519,11 @@ func (s *Server) buildIndexView(view string, heatMode string) indexView {
 		View:        view,
 		HeatMode:    heatMode,
 		ThemeClass:  s.themeClass,
+		ThemeVars:   s.themeVars,
+
+		ArchiveEnabled:     s.archiveDir != "",
+		SearchMinQuery:     s.searchMinQuery,
+		SessionsDirMissing: s.idx.BaseDirMissing(),
 	}
 }
 
@@ -552,6 +1567,75 @@ func buildDirViewsFromCounts(counts map[string]int, recentCounts map[string]int,
 	return views
 }
 
+// buildVersionViews counts sessions per CLI version, newest version first.
+func buildVersionViews(files []sessions.SessionFile, cwds []string, kind sessions.SessionKind) []versionView {
+	counts := map[string]int{}
+	for _, file := range files {
+		if version := sessions.CliVersionForFile(file); version != "" {
+			counts[version]++
+		}
+	}
+	views := make([]versionView, 0, len(counts))
+	for version, count := range counts {
+		views = append(views, versionView{Value: version, Count: count, Query: dayQuery(cwds, version, kind)})
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return sessions.CompareCliVersions(views[i].Value, views[j].Value) > 0
+	})
+	return views
+}
+
+// buildKindViews counts sessions per SessionKind in display order, leaving
+// out kinds no session has.
+func buildKindViews(files []sessions.SessionFile, cwds []string, version string) []kindView {
+	counts := map[sessions.SessionKind]int{}
+	for _, file := range files {
+		counts[file.Kind]++
+	}
+	var views []kindView
+	for _, kind := range sessions.SessionKinds {
+		if counts[kind] > 0 {
+			views = append(views, kindView{Value: kind, Count: counts[kind], Query: dayQuery(cwds, version, kind)})
+		}
+	}
+	return views
+}
+
+func cliOutdated(version, latest string) bool {
+	return version != "" && latest != "" && sessions.CompareCliVersions(version, latest) < 0
+}
+
+// gitFields returns the repo and branch detected for cwd, or empty strings.
+func (s *Server) gitFields(cwd string) (string, string) {
+	if cwd == "" {
+		return "", ""
+	}
+	info, ok := s.idx.Git(cwd)
+	if !ok {
+		return "", ""
+	}
+	return info.Repo, info.Branch
+}
+
+func (s *Server) withGit(views []dirView) []dirView {
+	for i := range views {
+		views[i].GitRepo, views[i].GitBranch = s.gitFields(views[i].Value)
+	}
+	return views
+}
+
+func (s *Server) withUnread(views []dirView) []dirView {
+	if s.lastSeen == nil {
+		return views
+	}
+	for i := range views {
+		if latest, ok := s.idx.Latest(views[i].Value); ok {
+			views[i].Unread = s.lastSeen.unread(views[i].Value, latest.ModTime)
+		}
+	}
+	return views
+}
+
 func dirLabel(cwd string) string {
 	if sessions.NormalizeCwd(cwd) == sessions.UnknownCwd {
 		return "Unknown (no CWD)"
@@ -559,6 +1643,21 @@ func dirLabel(cwd string) string {
 	return cwd
 }
 
+// sessionTitle names a session tab after its working directory's last
+// element and date, so tabs from different projects can be told apart;
+// sessions without a cwd use the file name instead.
+func sessionTitle(cwd string, date sessions.DateKey, fileName string) string {
+	label := fileName
+	if sessions.NormalizeCwd(cwd) != sessions.UnknownCwd {
+		trimmed := strings.TrimRight(cwd, `/\`)
+		label = trimmed[strings.LastIndexAny(trimmed, `/\`)+1:]
+		if label == "" {
+			label = cwd
+		}
+	}
+	return label + " · " + date.String() + " - Codex Session"
+}
+
 func displayCwd(cwd string) string {
 	if sessions.NormalizeCwd(cwd) == sessions.UnknownCwd {
 		return ""
@@ -608,20 +1707,53 @@ func (s *Server) recentCwdCountsFromLatestDates(limit int) (map[string]int, int)
 	return counts, max
 }
 
-func parseHeatMode(value string) string {
+// parseHeatMode normalizes a heat query value into its display label and lookback
+// window. "today" has no fixed window; callers anchor it to local midnight.
+// Unparseable values fall back to 7d.
+func parseHeatMode(value string) (string, time.Duration) {
+	if mode, window, ok := lookupHeatMode(value); ok {
+		return mode, window
+	}
+	return "7d", 7 * 24 * time.Hour
+}
+
+// lookupHeatMode is parseHeatMode without the 7d fallback for unknown values.
+func lookupHeatMode(value string) (string, time.Duration, bool) {
 	value = strings.ToLower(strings.TrimSpace(value))
 	switch value {
 	case "today":
-		return "today"
+		return "today", 0, true
 	case "1h", "1hr", "1hour":
-		return "1h"
+		return "1h", time.Hour, true
 	case "7d", "week", "7days":
-		return "7d"
-	default:
-		return "7d"
+		return "7d", 7 * 24 * time.Hour, true
+	}
+	if window, ok := parseHeatWindow(value); ok {
+		return value, window, true
 	}
+	return "", 0, false
 }
 
+// parseHeatWindow accepts a Go duration (e.g. 24h) or an <n>d day shorthand.
+func parseHeatWindow(value string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil || n <= 0 || n > math.MaxInt64/int64(24*time.Hour) {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		return 0, false
+	}
+	return window, true
+}
+
+// heatMinScale is the smallest denominator heatColor scales against, so a quiet
+// window where the busiest directory has one or two sessions does not saturate.
+const heatMinScale = 5
+
 func heatColor(count int, max int) template.CSS {
 	const (
 		hotR     = 210
@@ -633,7 +1765,11 @@ func heatColor(count int, max int) template.CSS {
 	if max <= 0 || count <= 0 {
 		return template.CSS("")
 	}
-	ratio := float64(count) / float64(max)
+	if max < heatMinScale {
+		max = heatMinScale
+	}
+	// Log scaling keeps mid-range directories distinguishable from the busiest one.
+	ratio := math.Log1p(float64(count)) / math.Log1p(float64(max))
 	if ratio > 1 {
 		ratio = 1
 	}
@@ -642,6 +1778,66 @@ func heatColor(count int, max int) template.CSS {
 	return template.CSS(fmt.Sprintf("rgba(%d, %d, %d, %.3f)", hotR, hotG, hotB, alpha))
 }
 
+// selectedCwds normalizes repeated cwd parameters, dropping blanks and duplicates.
+func selectedCwds(values []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, value := range values {
+		cwd := normalizeCwdParam(value)
+		if cwd == "" || seen[cwd] {
+			continue
+		}
+		seen[cwd] = true
+		out = append(out, cwd)
+	}
+	return out
+}
+
+// toggleCwd returns cwds with cwd removed if present, or appended otherwise.
+func toggleCwd(cwds []string, cwd string) []string {
+	out := make([]string, 0, len(cwds)+1)
+	found := false
+	for _, existing := range cwds {
+		if existing == cwd {
+			found = true
+			continue
+		}
+		out = append(out, existing)
+	}
+	if !found {
+		out = append(out, cwd)
+	}
+	return out
+}
+
+// cwdQuery encodes cwds as repeated cwd parameters ("" when there are none),
+// typed so templates keep the separators when it follows "?" in an href.
+func cwdQuery(cwds []string) template.URL {
+	if len(cwds) == 0 {
+		return ""
+	}
+	return template.URL(url.Values{"cwd": cwds}.Encode())
+}
+
+// dayQuery encodes the day page filters as "?cwd=…&type=…&version=…" ("" when
+// none are set).
+func dayQuery(cwds []string, version string, kind sessions.SessionKind) template.URL {
+	values := url.Values{}
+	if len(cwds) > 0 {
+		values["cwd"] = cwds
+	}
+	if version != "" {
+		values.Set("version", version)
+	}
+	if kind != "" {
+		values.Set("type", string(kind))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return template.URL("?" + values.Encode())
+}
+
 func normalizeCwdParam(value string) string {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -655,14 +1851,24 @@ func normalizeCwdParam(value string) string {
 	return value
 }
 
-func buildResumeCommand(meta *sessions.SessionMeta) string {
-	if meta == nil || meta.ID == "" {
-		return ""
-	}
-	if meta.Cwd != "" {
-		return fmt.Sprintf("cd %s\ncodex resume %s", shellQuote(meta.Cwd), meta.ID)
+// adjacentSessions returns links to the sessions listed just above and below
+// file on its day page (newest first), or "" at either end.
+func (s *Server) adjacentSessions(file sessions.SessionFile) (string, string) {
+	files := s.idx.SessionsByDate(file.Date)
+	for i, candidate := range files {
+		if candidate.Path != file.Path {
+			continue
+		}
+		var prev, next string
+		if i > 0 {
+			prev = "/" + file.Date.Path() + "/" + files[i-1].Name
+		}
+		if i+1 < len(files) {
+			next = "/" + file.Date.Path() + "/" + files[i+1].Name
+		}
+		return prev, next
 	}
-	return fmt.Sprintf("codex resume %s", meta.ID)
+	return "", ""
 }
 
 func shellQuote(value string) string {
@@ -672,13 +1878,17 @@ func shellQuote(value string) string {
 	return "'" + strings.ReplaceAll(value, "'", "'\"'\"'") + "'"
 }
 
-func (s *Server) buildSessionView(parts []string) (sessionPageView, error) {
+// buildSessionView renders a session page. With chat set, only message and
+// reasoning items are kept. With edgeItems > 0, a session of more than
+// 2*edgeItems (kept) items shows only the first and last edgeItems; the rest
+// are counted in HiddenItems.
+func (s *Server) buildSessionView(parts []string, edgeItems int, chat bool) (sessionPageView, error) {
 	date, ok := sessions.ParseDate(parts[0], parts[1], parts[2])
 	if !ok {
 		return sessionPageView{}, errors.New("invalid date")
 	}
 	filename := parts[3]
-	if filename == "" || strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
+	if !safeFilename(filename) {
 		return sessionPageView{}, errors.New("invalid filename")
 	}
 
@@ -686,43 +1896,76 @@ func (s *Server) buildSessionView(parts []string) (sessionPageView, error) {
 	if !ok {
 		return sessionPageView{}, errors.New("file not found")
 	}
+	if s.maxParseSize > 0 && file.Size > s.maxParseSize {
+		return sessionPageView{}, errSessionTooLarge
+	}
 
-	session, err := sessions.ParseSession(file.Path)
+	session, err := sessions.ParseSessionCached(file.Path)
 	if err != nil {
 		return sessionPageView{}, err
 	}
 
-	items := make([]itemView, 0, len(session.Items))
+	// Collapsing repeated tool results is a view concern: the Markdown copy
+	// below still lists every output. The collapsed lines lose their anchors.
+	source := sessions.DedupeToolOutputs(session.Items)
+	if chat {
+		source = chatItems(source)
+	}
+	hiddenItems, hiddenAt := 0, 0
+	if edgeItems > 0 && len(source) > 2*edgeItems {
+		hiddenItems, hiddenAt = len(source)-2*edgeItems, edgeItems
+	}
+	items := make([]itemView, 0, len(source)-hiddenItems)
 	lastUserLine := 0
 	lastAnyUserLine := 0
-	for _, item := range session.Items {
+	for i, item := range source {
 		autoCtx := item.Role == "user" && sessions.IsAutoContextUserMessage(item.Content)
+		if item.Role == "user" {
+			lastAnyUserLine = item.Line
+			if !autoCtx {
+				lastUserLine = item.Line
+			}
+		}
+		if i >= hiddenAt && i < hiddenAt+hiddenItems {
+			continue
+		}
 		renderText := item.Content
 		if autoCtx {
 			renderText = escapeAutoContextTags(renderText)
 		}
 		view := itemView{
 			Line:      item.Line,
-			Timestamp: item.Timestamp,
+			Timestamp: s.formatItemTimestamp(item.Timestamp),
 			Type:      item.Type,
 			Subtype:   item.Subtype,
 			Role:      item.Role,
 			Title:     item.Title,
 			Content:   item.Content,
 			Class:     item.Class,
+			Hidden:    item.Hidden,
+			Aborted:   item.Aborted,
 			Markdown:  renderItemMarkdown(item),
 			HTML:      markdownToHTML(renderText),
+
+			Unrecognized: item.Unrecognized,
+			Repeated:     item.Repeated,
+		}
+		switch strings.ToLower(item.Role) {
+		case "user":
+			view.IsUser = true
+		case "assistant":
+			view.IsAssistant = true
+		case "tool":
+			view.IsTool = true
+		}
+		if item.Output != "" {
+			view.OutputHTML = markdownToHTML(item.Output)
+			view.OutputLine = item.OutputLine
 		}
 		if autoCtx {
 			view.AutoCtx = true
 			view.Class = strings.TrimSpace(view.Class + " auto-context")
 		}
-		if item.Role == "user" {
-			lastAnyUserLine = item.Line
-			if !autoCtx {
-				lastUserLine = item.Line
-			}
-		}
 		items = append(items, view)
 	}
 	if lastUserLine == 0 {
@@ -738,20 +1981,101 @@ func (s *Server) buildSessionView(parts []string) (sessionPageView, error) {
 		File: sessionView{
 			Name:    file.Name,
 			Size:    formatBytes(file.Size),
-			ModTime: formatTime(file.ModTime),
+			ModTime: s.formatTime(file.ModTime),
 			Cwd:     displayCwd(sessions.CwdForFile(file)),
 		},
 		Meta:          session.Meta,
 		Items:         items,
-		AllMarkdown:   renderSessionMarkdown(session.Items),
+		AllMarkdown:   RenderSessionMarkdown(session.Items),
 		ResumeCommand: buildResumeCommand(session.Meta),
 		ThemeClass:    s.themeClass,
+		ThemeVars:     s.themeVars,
 		IsJSONL:       strings.HasSuffix(strings.ToLower(file.Name), ".jsonl"),
 		LastUserLine:  lastUserLine,
+		Related:       s.relatedSessions(file),
+		EditorEnabled: s.editor != nil && !s.readOnly,
+		UnparsedLines: session.UnparsedLines,
+		SearchEnabled: s.search != nil,
+	}
+	view.ArchiveEnabled = s.archiveDir != "" && !s.readOnly
+	view.ReadOnly = s.readOnly
+	view.Title = sessionTitle(sessions.CwdForFile(file), date, file.Name)
+	view.Cwds = session.Cwds
+	view.HiddenItems, view.HiddenAt = hiddenItems, hiddenAt
+	view.Chat = chat
+	view.PrevSession, view.NextSession = s.adjacentSessions(file)
+	view.File.GitRepo, view.File.GitBranch = s.gitFields(sessions.CwdForFile(file))
+	if session.Meta != nil && session.Meta.CliVersion != "" {
+		view.File.CliVersion = strings.TrimSpace(session.Meta.CliVersion)
+		view.File.OutdatedCli = cliOutdated(view.File.CliVersion, s.idx.LatestCliVersion())
+	}
+
+	if session.Meta != nil && strings.TrimSpace(session.Meta.Instructions) != "" {
+		view.Instructions = markdownToHTML(session.Meta.Instructions)
+		view.InstructionsLine = session.InstructionsLine
 	}
 	return view, nil
 }
 
+// chatItems keeps the dialogue of a session: messages and reasoning, without
+// tool calls, tool outputs, or other events.
+func chatItems(items []sessions.RenderItem) []sessions.RenderItem {
+	kept := make([]sessions.RenderItem, 0, len(items))
+	for _, item := range items {
+		if item.Subtype == "message" || item.Subtype == "reasoning" {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// relatedSessions returns the most recently modified sessions sharing file's cwd.
+func (s *Server) relatedSessions(file sessions.SessionFile) []relatedView {
+	cwd := sessions.CwdForFile(file)
+	if cwd == sessions.UnknownCwd {
+		return nil
+	}
+	siblings := s.idx.SessionsByCwd(cwd)
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].ModTime.After(siblings[j].ModTime)
+	})
+	views := make([]relatedView, 0, relatedLimit)
+	for _, sibling := range siblings {
+		if sibling.Date == file.Date && sibling.Name == file.Name {
+			continue
+		}
+		views = append(views, relatedView{
+			Date:    sibling.Date.String(),
+			Path:    sibling.Date.Path(),
+			Name:    sibling.Name,
+			ModTime: s.formatTime(sibling.ModTime),
+			Summary: sibling.Summary,
+		})
+		if len(views) == relatedLimit {
+			break
+		}
+	}
+	return views
+}
+
+// SetThemeColors overrides the selected theme's text (primary), background,
+// and accent colors; empty values keep the theme's own. Colors must already be
+// validated (config.Parse does), since they are emitted into a style block.
+func (s *Server) SetThemeColors(primary, background, accent string) {
+	var vars []string
+	if primary != "" {
+		vars = append(vars, "--ink: "+primary+";")
+	}
+	if background != "" {
+		// The page gradient fades from --bg-glow, so both follow the background.
+		vars = append(vars, "--bg: "+background+";", "--bg-glow: "+background+";")
+	}
+	if accent != "" {
+		vars = append(vars, "--accent: "+accent+";")
+	}
+	s.themeVars = template.CSS(strings.Join(vars, " "))
+}
+
 func themeClass(theme int) string {
 	switch theme {
 	case 1:
@@ -771,6 +2095,55 @@ func themeClass(theme int) string {
 	}
 }
 
+const (
+	// shareTokenBytes is the random token size for share filenames; 16 bytes
+	// format as a UUID, larger sizes fall back to plain hex.
+	shareTokenBytes = 16
+	// shareCreateAttempts bounds retries when a generated filename already exists.
+	shareCreateAttempts = 5
+)
+
+// newShareToken is swapped out in tests to force filename collisions.
+var newShareToken = func() (string, error) {
+	return randomToken(shareTokenBytes)
+}
+
+// createShareFile writes data to a fresh, unguessable filename in dir. Files are
+// opened with O_EXCL so an existing share is never overwritten, and chmodded to
+// mode afterwards so the umask cannot narrow it.
+func createShareFile(dir string, data []byte, mode os.FileMode) (string, error) {
+	for attempt := 0; attempt < shareCreateAttempts; attempt++ {
+		token, err := newShareToken()
+		if err != nil {
+			return "", fmt.Errorf("create share token: %w", err)
+		}
+		fileName := formatUUID(token) + ".html"
+		file, err := os.OpenFile(filepath.Join(dir, fileName), os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := file.Chmod(mode); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return "", err
+		}
+		if err := file.Close(); err != nil {
+			os.Remove(file.Name())
+			return "", err
+		}
+		return fileName, nil
+	}
+	return "", errors.New("could not find an unused share filename")
+}
+
 func randomToken(size int) (string, error) {
 	buf := make([]byte, size)
 	if _, err := rand.Read(buf); err != nil {
@@ -794,6 +2167,9 @@ func (s *Server) buildShareURL(r *http.Request, filename string) string {
 	if r.TLS != nil {
 		scheme = "https"
 	}
+	if s.singlePortShares {
+		return fmt.Sprintf("%s://%s%s%s", scheme, r.Host, sharedPathPrefix, filename)
+	}
 
 	host := r.Host
 	hostName := host
@@ -822,10 +2198,14 @@ func renderItemMarkdown(item sessions.RenderItem) string {
 	if content == "" {
 		content = "(empty)"
 	}
+	if output := strings.TrimSpace(item.Output); output != "" {
+		content += "\n\n### Output\n\n" + output
+	}
 	return fmt.Sprintf("## %s\n\n%s\n", title, content)
 }
 
-func renderSessionMarkdown(items []sessions.RenderItem) string {
+// RenderSessionMarkdown formats items as a single Markdown document.
+func RenderSessionMarkdown(items []sessions.RenderItem) string {
 	if len(items) == 0 {
 		return ""
 	}
@@ -852,10 +2232,26 @@ var markdownEngine = goldmark.New(
 	goldmark.WithExtensions(extension.GFM),
 )
 
+// markdownPolicy is applied to goldmark's output before it reaches a page or
+// share file. goldmark already drops raw HTML and javascript: links; this is
+// the backstop should that change. On top of bluemonday's user-content
+// policy it keeps code block language classes, GFM table alignment and
+// task-list checkboxes, and data: images.
+var markdownPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#.-]+$`)).OnElements("code")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.AllowAttrs("style").OnElements("th", "td")
+	policy.AllowStyles("text-align").MatchingEnum("left", "right", "center").OnElements("th", "td")
+	policy.AllowDataURIImages()
+	return policy
+}()
+
 func markdownToHTML(text string) template.HTML {
 	var buf bytes.Buffer
 	if err := markdownEngine.Convert([]byte(text), &buf); err != nil {
 		return template.HTML(html.EscapeString(text))
 	}
-	return template.HTML(buf.String())
+	return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes()))
 }
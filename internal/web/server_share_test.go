@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"codex-manager/internal/render"
 	"codex-manager/internal/sessions"
@@ -15,6 +18,8 @@ import (
 	"net/http/httptest"
 )
 
+const testShareCSP = "default-src 'self'; style-src 'self' 'unsafe-inline'"
+
 type fakeHTMLBucketUploader struct {
 	url   string
 	err   error
@@ -73,6 +78,59 @@ func TestHandleShareLocalWritesFile(t *testing.T) {
 	}
 }
 
+func TestSinglePortShares(t *testing.T) {
+	sessionsDir := t.TempDir()
+	shareDir := filepath.Join(t.TempDir(), "shares")
+	datePath, fileName := writeTestSession(t, sessionsDir)
+
+	idx := sessions.NewIndex(sessionsDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	renderer, err := render.New()
+	if err != nil {
+		t.Fatalf("renderer: %v", err)
+	}
+	server := NewServer(idx, nil, renderer, sessionsDir, shareDir, ":8081", 3)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shared/missing.html", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected /shared/ to 404 without single-port, got %d", rec.Code)
+	}
+
+	server.EnableSinglePortShares(testShareCSP)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com:8080/share/"+datePath+"/"+fileName, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d body %s", rec.Code, rec.Body.String())
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	shareName, ok := strings.CutPrefix(payload["url"], "http://example.com:8080/shared/")
+	if !ok {
+		t.Fatalf("expected a same-origin share url, got %q", payload["url"])
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shared/"+shareName, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<html") {
+		t.Fatalf("expected the share page, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Robots-Tag") == "" || rec.Header().Get("Content-Security-Policy") != testShareCSP {
+		t.Fatalf("expected X-Robots-Tag and CSP headers on shared pages")
+	}
+	for _, target := range []string{"/shared/..%2f" + shareName, "/shared/x/" + shareName, "/shared/"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+}
+
 func TestHandleShareHTMLBucketSuccess(t *testing.T) {
 	sessionsDir := t.TempDir()
 	shareDir := filepath.Join(t.TempDir(), "shares")
@@ -163,6 +221,136 @@ func TestHandleShareHTMLBucketFailure(t *testing.T) {
 	}
 }
 
+func TestHandleShareDay(t *testing.T) {
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	writeSessionWithCwd(t, sessionsDir, datePath, "second.jsonl", "/proj", time.Now())
+	// A session without conversation is left out of the index.
+	if err := os.WriteFile(filepath.Join(sessionsDir, datePath, "empty.jsonl"), []byte("{\"type\":\"session_meta\",\"payload\":{\"id\":\"e\"}}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+	shareDir := server.shareDir
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d body %s", rec.Code, rec.Body.String())
+	}
+	var payload struct {
+		URL      string `json:"url"`
+		Sessions int    `json:"sessions"`
+		Skipped  int    `json:"skipped"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Sessions != 2 || payload.Skipped != 1 {
+		t.Fatalf("unexpected counts: %+v", payload)
+	}
+	entries, err := os.ReadDir(shareDir)
+	if err != nil {
+		t.Fatalf("read share dir: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected two session shares and an index, got %d files", len(entries))
+	}
+	indexName := payload.URL[strings.LastIndex(payload.URL, "/")+1:]
+	index, err := os.ReadFile(filepath.Join(shareDir, indexName))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == indexName {
+			continue
+		}
+		if !strings.Contains(string(index), `href="`+entry.Name()+`"`) {
+			t.Fatalf("expected the index to link %s:\n%s", entry.Name(), index)
+		}
+	}
+	if !strings.Contains(string(index), fileName) || strings.Contains(string(index), "empty.jsonl") {
+		t.Fatalf("unexpected index listing:\n%s", index)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/2026/02/01", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a day without sessions, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a cross-site day share, got %d", rec.Code)
+	}
+
+	// Two session shares and the index cost three tokens, more than a
+	// bucket of two ever holds.
+	server.SetShareRateLimit(2)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil))
+	if rec.Code != http.StatusRequestEntityTooLarge || rec.Header().Get("Retry-After") != "" || !strings.Contains(rec.Body.String(), "-share-rate") {
+		t.Fatalf("expected 413 naming -share-rate when the day exceeds the burst, got %d %s", rec.Code, rec.Body.String())
+	}
+	if after, _ := os.ReadDir(shareDir); len(after) != len(entries) {
+		t.Fatalf("expected nothing published over budget, got %d files", len(after))
+	}
+
+	// With one token spent, three no longer fit until the bucket refills.
+	server.SetShareRateLimit(3)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share/"+datePath+"/"+fileName, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("share: got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 once the bucket runs short, got %d", rec.Code)
+	}
+
+	server.SetShareRateLimit(3)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 within budget, got %d", rec.Code)
+	}
+
+	// Too-large sessions are skipped before charging: one page and the
+	// index fit a bucket of two.
+	small, err := os.Stat(filepath.Join(sessionsDir, datePath, fileName))
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	big := filepath.Join(sessionsDir, datePath, "second.jsonl")
+	data, err := os.ReadFile(big)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	data = append(data, fmt.Sprintf("{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":%q}]}}\n", strings.Repeat("x", int(small.Size())))...)
+	if err := os.WriteFile(big, data, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	server.SetMaxParseSize(small.Size())
+	server.SetShareRateLimit(2)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "http://example.com/share-day/"+datePath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected too-large sessions left uncharged, got %d %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if payload.Sessions != 1 || payload.Skipped != 2 {
+		t.Fatalf("unexpected counts with a too-large session: %+v", payload)
+	}
+}
+
 func writeTestSession(t *testing.T, sessionsDir string) (string, string) {
 	t.Helper()
 	datePath := filepath.Join("2026", "01", "09")
@@ -181,3 +369,195 @@ func writeTestSession(t *testing.T, sessionsDir string) (string, string) {
 	}
 	return filepath.ToSlash(datePath), fileName
 }
+
+func TestShareServerRobots(t *testing.T) {
+	shareDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(shareDir, "abc.html"), []byte("<p>hi</p>"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	handler := NewShareServer(shareDir, testShareCSP)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/robots.txt", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("robots status: got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Disallow: /") {
+		t.Fatalf("unexpected robots body: %q", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "http://example.com/abc.html", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("share status: got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Robots-Tag"); got != "noindex, nofollow" {
+		t.Fatalf("unexpected X-Robots-Tag: %q", got)
+	}
+}
+
+func TestShareServerCSP(t *testing.T) {
+	shareDir := t.TempDir()
+	for _, name := range []string{"abc.html", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(shareDir, name), []byte("hi"), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	for _, tc := range []struct {
+		csp, target, want string
+	}{
+		{testShareCSP, "/abc.html", testShareCSP},
+		{testShareCSP, "/notes.txt", ""},
+		{"", "/abc.html", ""},
+	} {
+		rec := httptest.NewRecorder()
+		NewShareServer(shareDir, tc.csp).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", tc.target, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Security-Policy"); got != tc.want {
+			t.Fatalf("csp %q, %s: expected header %q, got %q", tc.csp, tc.target, tc.want, got)
+		}
+	}
+}
+
+func TestHandleShareMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permission bits")
+	}
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	server := newTestServer(t, sessionsDir)
+	// An existing private dir is widened along with the files.
+	if err := os.MkdirAll(server.shareDir, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	server.SetShareMode(0o644)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/share/"+datePath+"/"+fileName, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status: got %d body %s", rec.Code, rec.Body.String())
+	}
+	info, err := os.Stat(server.shareDir)
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Fatalf("expected share dir 0755, got %v (%v)", info.Mode().Perm(), err)
+	}
+	entries, err := os.ReadDir(server.shareDir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 share file, got %d (%v)", len(entries), err)
+	}
+	info, err = entries[0].Info()
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("expected share file 0644, got %v (%v)", info.Mode().Perm(), err)
+	}
+}
+
+func TestCreateShareFileSkipsExisting(t *testing.T) {
+	dir := t.TempDir()
+	tokens := []string{strings.Repeat("a", 32), strings.Repeat("a", 32), strings.Repeat("b", 32)}
+	original := newShareToken
+	newShareToken = func() (string, error) {
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token, nil
+	}
+	defer func() { newShareToken = original }()
+
+	first, err := createShareFile(dir, []byte("first"), defaultShareMode)
+	if err != nil {
+		t.Fatalf("first share: %v", err)
+	}
+	second, err := createShareFile(dir, []byte("second"), defaultShareMode)
+	if err != nil {
+		t.Fatalf("second share: %v", err)
+	}
+	if first == second {
+		t.Fatalf("expected collision to pick a new filename, got %s twice", first)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, first))
+	if err != nil || string(data) != "first" {
+		t.Fatalf("expected first share to be preserved, got %q (%v)", data, err)
+	}
+}
+
+func TestHandleShareConcurrency(t *testing.T) {
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	server := newTestServer(t, sessionsDir)
+	server.SetShareConcurrency(1)
+	defer func(wait time.Duration) { shareSlotWait = wait }(shareSlotWait)
+	shareSlotWait = 10 * time.Millisecond
+
+	share := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec
+	}
+
+	// Hold the only slot, as a share still rendering would.
+	release, ok := server.acquireShareSlot(httptest.NewRequest(http.MethodPost, "/", nil))
+	if !ok {
+		t.Fatalf("expected a free slot")
+	}
+	for _, target := range []string{"/share/" + datePath + "/" + fileName, "/share-day/" + datePath} {
+		rec := share(target)
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+			t.Fatalf("POST %s: expected 503 with Retry-After while saturated, got %d", target, rec.Code)
+		}
+	}
+	if entries, err := os.ReadDir(server.shareDir); err == nil && len(entries) > 0 {
+		t.Fatalf("expected nothing rendered while saturated, got %d files", len(entries))
+	}
+
+	release()
+	if rec := share("/share/" + datePath + "/" + fileName); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 once the slot is free, got %d", rec.Code)
+	}
+	if len(server.shareSlots) != 0 {
+		t.Fatalf("expected the slot released after the share")
+	}
+
+	server.SetShareConcurrency(0)
+	if rec := share("/share/" + datePath + "/" + fileName); rec.Code != http.StatusOK {
+		t.Fatalf("expected no limit with 0, got %d", rec.Code)
+	}
+}
+
+func TestHandleShareRateLimit(t *testing.T) {
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	server := newTestServer(t, sessionsDir)
+	server.SetShareRateLimit(2)
+	now := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	server.shareLimit.now = func() time.Time { return now }
+
+	share := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/share/"+datePath+"/"+fileName, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := share("10.0.0.1:5000"); code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+	if code := share("10.0.0.1:5001"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the bucket is empty, got %d", code)
+	}
+	if code := share("10.0.0.2:5000"); code != http.StatusOK {
+		t.Fatalf("expected other clients to be unaffected, got %d", code)
+	}
+	now = now.Add(30 * time.Second)
+	if code := share("10.0.0.1:5000"); code != http.StatusOK {
+		t.Fatalf("expected a token after refill, got %d", code)
+	}
+	if code := share("10.0.0.1:5000"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 again, got %d", code)
+	}
+}
@@ -0,0 +1,1429 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"codex-manager/internal/render"
+	"codex-manager/internal/search"
+	"codex-manager/internal/sessions"
+)
+
+func TestParseHeatMode(t *testing.T) {
+	cases := []struct {
+		in     string
+		label  string
+		window time.Duration
+	}{
+		{"", "7d", 7 * 24 * time.Hour},
+		{"today", "today", 0},
+		{"1hr", "1h", time.Hour},
+		{"week", "7d", 7 * 24 * time.Hour},
+		{"24h", "24h", 24 * time.Hour},
+		{"30d", "30d", 30 * 24 * time.Hour},
+		{"90m", "90m", 90 * time.Minute},
+		{"-3h", "7d", 7 * 24 * time.Hour},
+		{"0d", "7d", 7 * 24 * time.Hour},
+		{"bogus", "7d", 7 * 24 * time.Hour},
+	}
+	for _, tc := range cases {
+		label, window := parseHeatMode(tc.in)
+		if label != tc.label || window != tc.window {
+			t.Fatalf("parseHeatMode(%q): got (%q, %v) want (%q, %v)", tc.in, label, window, tc.label, tc.window)
+		}
+	}
+}
+
+func TestIndexDefaults(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec.Body.String()
+	}
+	if body := get("/"); !strings.Contains(body, "Available Directories") {
+		t.Fatalf("expected directory view by default")
+	}
+	if err := server.SetIndexDefaults("date", "week"); err != nil {
+		t.Fatalf("SetIndexDefaults: %v", err)
+	}
+	if body := get("/"); !strings.Contains(body, "Available Dates") {
+		t.Fatalf("expected configured date view")
+	}
+	if body := get("/?view=dir"); !strings.Contains(body, "Available Directories") {
+		t.Fatalf("expected explicit view param to win")
+	}
+	if err := server.SetIndexDefaults("dir", "week"); err != nil || server.defaultHeat != "7d" {
+		t.Fatalf("expected week to normalize to 7d, got %q (%v)", server.defaultHeat, err)
+	}
+	for _, args := range [][2]string{{"list", "1h"}, {"dir", "bogus"}, {"dir", "-3h"}} {
+		if err := server.SetIndexDefaults(args[0], args[1]); err == nil {
+			t.Fatalf("SetIndexDefaults(%q, %q): expected error", args[0], args[1])
+		}
+	}
+}
+
+func TestIndexMissingSessionsDir(t *testing.T) {
+	sessionsDir := filepath.Join(t.TempDir(), "sessions")
+	idx := sessions.NewIndex(sessionsDir)
+	if err := idx.Refresh(); err == nil {
+		t.Fatalf("expected refresh error for missing dir")
+	}
+	renderer, err := render.New()
+	if err != nil {
+		t.Fatalf("renderer: %v", err)
+	}
+	server := NewServer(idx, nil, renderer, sessionsDir, filepath.Join(t.TempDir(), "shares"), ":8081", 3)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Body.String()
+	}
+	if body := get(); !strings.Contains(body, "Sessions directory not found: "+sessionsDir) {
+		t.Fatalf("expected missing directory notice, got %s", body)
+	}
+
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if body := get(); strings.Contains(body, "Sessions directory not found") || !strings.Contains(body, "/proj") {
+		t.Fatalf("expected recovery after the directory appeared, got %s", body)
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+	server.EnableArchive(filepath.Join(t.TempDir(), "archive"))
+	server.SetReadOnly(true)
+
+	for _, target := range []string{"/share/2026/01/09/a.jsonl", "/share-day/2026/01/09", "/archive/2026/01/09/a.jsonl", "/shares/revoke/x.html"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("POST %s: expected 403, got %d", target, rec.Code)
+		}
+	}
+	if entries, err := os.ReadDir(server.shareDir); err == nil && len(entries) > 0 {
+		t.Fatalf("expected no shares written, got %d", len(entries))
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/refresh", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected rescans to keep working, got %d", rec.Code)
+	}
+	for _, target := range []string{"/2026/01/09/a.jsonl", "/2026/01/09/"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, rec.Code)
+		}
+		if strings.Contains(body, `action="/share/`) || strings.Contains(body, `action="/archive/`) {
+			t.Fatalf("GET %s: expected mutating forms to be hidden", target)
+		}
+	}
+}
+
+func TestSameNameDifferentCwdKeepsBothFiles(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "work/2026/01/09", "same.jsonl", "/proj/work", now.Add(-time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "home/2026/01/09", "same.jsonl", "/proj/home", now)
+
+	pattern, err := sessions.ParsePathPattern("{account}/{year}/{month}/{day}")
+	if err != nil {
+		t.Fatalf("parse pattern: %v", err)
+	}
+	idx := sessions.NewIndex(sessionsDir)
+	idx.SetPathPattern(pattern)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	renderer, err := render.New()
+	if err != nil {
+		t.Fatalf("renderer: %v", err)
+	}
+	server := NewServer(idx, nil, renderer, sessionsDir, t.TempDir(), ":8081", 3)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	// Both files are listed under their own cwd; only the newest is linked,
+	// and the session page and resume command describe that one file.
+	day := get("/2026/01/09/")
+	if strings.Count(day, `href="/2026/01/09/same.jsonl"`) != 1 || !strings.Contains(day, "/proj/home") || !strings.Contains(day, "/proj/work") || !strings.Contains(day, "Name in use") {
+		t.Fatalf("expected both rows with the older one marked, got %s", day)
+	}
+	if filtered := get("/2026/01/09/?cwd=/proj/work"); !strings.Contains(filtered, "Name in use") || strings.Contains(filtered, `href="/2026/01/09/same.jsonl"`) {
+		t.Fatalf("expected the work row, unlinked, under its own cwd")
+	}
+	if filtered := get("/2026/01/09/?cwd=/proj/home"); !strings.Contains(filtered, `href="/2026/01/09/same.jsonl"`) || strings.Contains(filtered, "Name in use") {
+		t.Fatalf("expected the linked home row under its own cwd")
+	}
+	page := get("/2026/01/09/same.jsonl")
+	if !strings.Contains(page, "codex resume same.jsonl") || !strings.Contains(page, "/proj/home") || strings.Contains(page, "/proj/work") {
+		t.Fatalf("expected resume command for the listed file, got %s", page)
+	}
+}
+
+func TestDayViewEmptySessions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "chat.jsonl", "/proj", time.Now())
+	emptyPath := filepath.Join(sessionsDir, "2026", "01", "09", "empty.jsonl")
+	if err := os.WriteFile(emptyPath, []byte("{\"type\":\"session_meta\",\"payload\":{\"id\":\"e\",\"cwd\":\"/proj\"}}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/", nil))
+		return rec.Body.String()
+	}
+	body := get()
+	if strings.Count(body, `class="tag tag-empty"`) != 1 || !strings.Contains(body, "empty.jsonl") {
+		t.Fatalf("expected one Empty badge, got %s", body)
+	}
+	server.SetHideEmpty(true)
+	body = get()
+	if strings.Contains(body, "empty.jsonl") || !strings.Contains(body, "chat.jsonl") || !strings.Contains(body, "1 empty session hidden.") {
+		t.Fatalf("expected empty session hidden, got %s", body)
+	}
+}
+
+func TestRelatedSessions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", now.Add(-3*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/proj", now.Add(-1*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "c.jsonl", "/proj", now.Add(-2*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "d.jsonl", "/other", now)
+
+	idx := sessions.NewIndex(sessionsDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	server := NewServer(idx, nil, nil, sessionsDir, "", ":8081", 3)
+
+	date, _ := sessions.ParseDate("2026", "01", "09")
+	file, ok := idx.Lookup(date, "a.jsonl")
+	if !ok {
+		t.Fatalf("lookup failed")
+	}
+	related := server.relatedSessions(file)
+	if len(related) != 2 {
+		t.Fatalf("expected 2 related sessions, got %d", len(related))
+	}
+	if related[0].Name != "b.jsonl" || related[1].Name != "c.jsonl" {
+		t.Fatalf("unexpected related order: %+v", related)
+	}
+}
+
+func TestHandleSessionTooLarge(t *testing.T) {
+	sessionsDir := t.TempDir()
+	datePath, fileName := writeTestSession(t, sessionsDir)
+	server := newTestServer(t, sessionsDir)
+	server.SetMaxParseSize(16)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/"+datePath+"/"+fileName, nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status: got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "/raw/"+datePath+"/"+fileName) {
+		t.Fatalf("expected raw download link in body")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "http://example.com/share/"+datePath+"/"+fileName, nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("share status: got %d", rec.Code)
+	}
+}
+
+func newTestServer(t *testing.T, sessionsDir string) *Server {
+	t.Helper()
+	idx := sessions.NewIndex(sessionsDir)
+	if err := idx.Refresh(); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	renderer, err := render.New()
+	if err != nil {
+		t.Fatalf("renderer: %v", err)
+	}
+	return NewServer(idx, nil, renderer, sessionsDir, filepath.Join(t.TempDir(), "shares"), ":8081", 3)
+}
+
+func writeSessionWithCwd(t *testing.T, sessionsDir, datePath, name, cwd string, modTime time.Time) string {
+	t.Helper()
+	fullDir := filepath.Join(sessionsDir, filepath.FromSlash(datePath))
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data := fmt.Sprintf("{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"session_meta\",\"payload\":{\"id\":%q,\"cwd\":%q}}\n", name, cwd) +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hello\"}]}}\n"
+	fullPath := filepath.Join(fullDir, name)
+	if err := os.WriteFile(fullPath, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Chtimes(fullPath, modTime, modTime); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	return fullPath
+}
+
+func TestHandleOpenEditor(t *testing.T) {
+	sessionsDir := t.TempDir()
+	cwd := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", cwd, time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/open/2026/01/09/a.jsonl", nil)
+	req.RemoteAddr = "127.0.0.1:5555"
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("disabled: got %d", rec.Code)
+	}
+
+	if _, err := exec.LookPath("true"); err != nil {
+		t.Skip("true binary not available")
+	}
+	if err := server.EnableEditor("true {{.Cwd}}"); err != nil {
+		t.Fatalf("EnableEditor: %v", err)
+	}
+	args, err := server.editor.args("/path with space")
+	if err != nil || len(args) != 3 || args[1] != "--" || args[2] != "/path with space" {
+		t.Fatalf("unexpected args: %q (%v)", args, err)
+	}
+
+	for _, header := range []struct{ name, value string }{
+		{"Origin", "https://evil.example"},
+		{"Origin", "null"},
+		{"Sec-Fetch-Site", "cross-site"},
+	} {
+		req = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/open/2026/01/09/a.jsonl", nil)
+		req.RemoteAddr = "127.0.0.1:5555"
+		req.Header.Set(header.name, header.value)
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403, got %d", header.name, header.value, rec.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/open/2026/01/09/a.jsonl", nil)
+	req.RemoteAddr = "192.168.1.20:5555"
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("remote client: got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/open/2026/01/09/a.jsonl", nil)
+	req.RemoteAddr = "127.0.0.1:5555"
+	req.Header.Set("Origin", "http://127.0.0.1")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("enabled: got %d body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFormatItemTimestamp(t *testing.T) {
+	server := NewServer(nil, nil, nil, "", "", ":8081", 3)
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	server.SetLocation(loc)
+
+	cases := map[string]string{
+		"2026-01-09T01:00:00Z":      "2026-01-09 03:00:00",
+		"2025-08-27T16:17:00.964Z":  "2025-08-27 18:17:00",
+		"2026-01-09T01:00:00-05:00": "2026-01-09 08:00:00",
+		"t1":                        "t1",
+		"":                          "",
+	}
+	for in, want := range cases {
+		if got := server.formatItemTimestamp(in); got != want {
+			t.Fatalf("formatItemTimestamp(%q): got %q want %q", in, got, want)
+		}
+	}
+}
+
+func TestHandleSearchHTML(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?query=hello&format=html", nil))
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("expected html content type, got %q", got)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `href="/2026/01/09/a.jsonl#line-2"`) {
+		t.Fatalf("expected session link, got %q", body)
+	}
+	if !strings.Contains(body, "<mark>Hello</mark>") {
+		t.Fatalf("expected highlighted preview, got %q", body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/search?query=hello", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("expected Accept header to select html, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?query=hello", nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected json by default, got %q", got)
+	}
+}
+
+func TestHighlightSegments(t *testing.T) {
+	cases := []struct {
+		text  string
+		query string
+		want  []previewSegment
+	}{
+		{"Foo bar foo", "foo", []previewSegment{{Text: "Foo", Match: true}, {Text: " bar "}, {Text: "foo", Match: true}}},
+		{"hit the rate limit", `limit "the rate"`, []previewSegment{{Text: "hit "}, {Text: "the rate", Match: true}, {Text: " "}, {Text: "limit", Match: true}}},
+	}
+	for _, tc := range cases {
+		segments := highlightSegments(tc.text, tc.query)
+		if len(segments) != len(tc.want) {
+			t.Fatalf("%q: got %+v want %+v", tc.query, segments, tc.want)
+		}
+		for i := range tc.want {
+			if segments[i] != tc.want[i] {
+				t.Fatalf("%q segment %d: got %+v want %+v", tc.query, i, segments[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestHandleUsage(t *testing.T) {
+	sessionsDir := t.TempDir()
+	path := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString("{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"event_msg\",\"payload\":{\"type\":\"token_count\",\"info\":{\"total_token_usage\":{\"input_tokens\":1200,\"output_tokens\":300,\"total_tokens\":1500}}}}\n")
+	f.Close()
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?from=2026-01-01", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report sessions.UsageReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if report.Sessions != 1 || report.Total.TotalTokens != 1500 || report.From != "2026-01-01" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?format=html", nil))
+	if !strings.Contains(rec.Body.String(), "1,500") {
+		t.Fatalf("expected formatted total in html, got %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/usage?to=bogus", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad date, got %d", rec.Code)
+	}
+}
+
+func TestHeatColorScaling(t *testing.T) {
+	if got := heatColor(0, 3); got != "" {
+		t.Fatalf("expected no color for zero count, got %q", got)
+	}
+	quiet := string(heatColor(1, 1))
+	if quiet == "rgba(210, 55, 50, 0.920)" {
+		t.Fatalf("expected a single-session max not to saturate, got %q", quiet)
+	}
+	if got := string(heatColor(20, 20)); got != "rgba(210, 55, 50, 0.920)" {
+		t.Fatalf("expected dominant directory to reach alphaMax, got %q", got)
+	}
+	if heatColor(2, 20) == heatColor(10, 20) {
+		t.Fatalf("expected distinct colors for different counts")
+	}
+}
+
+func TestSessionPageShowsUnparsedLines(t *testing.T) {
+	sessionsDir := t.TempDir()
+	path := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString("{broken\nalso broken\n")
+	f.Close()
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "2 lines could not be parsed") {
+		t.Fatalf("expected unparsed line indicator, got %q", body)
+	}
+	if !strings.Contains(body, "Hello") {
+		t.Fatalf("expected parseable items to still render")
+	}
+}
+
+func TestHandleLatest(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "old.jsonl", "/proj", now.Add(-2*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "proj.jsonl", "/proj", now.Add(-1*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/08", "newest.jsonl", "/other", now)
+	server := newTestServer(t, sessionsDir)
+
+	cases := []struct {
+		target   string
+		location string
+	}{
+		{"/latest", "/2026/01/08/newest.jsonl"},
+		{"/latest?cwd=/proj", "/2026/01/10/proj.jsonl"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("%s: expected 302, got %d", tc.target, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tc.location {
+			t.Fatalf("%s: expected %s, got %s", tc.target, tc.location, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest?cwd=/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown cwd, got %d", rec.Code)
+	}
+}
+
+func TestHandleToday(t *testing.T) {
+	sessionsDir := t.TempDir()
+	server := newTestServer(t, sessionsDir)
+	loc := time.FixedZone("UTC+14", 14*60*60)
+	server.SetLocation(loc)
+
+	datePath := time.Now().In(loc).Format("2006/01/02")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/today", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/"+datePath+"/" {
+		t.Fatalf("expected /%s/, got %s", datePath, got)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+datePath+"/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected day page 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "No sessions found") {
+		t.Fatalf("expected the empty state for a day without sessions")
+	}
+}
+
+func TestHandleSessionByID(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/10", "b.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/id/b.jsonl", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected 302, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/2026/01/10/b.jsonl" {
+		t.Fatalf("unexpected location %q", got)
+	}
+
+	for _, target := range []string{"/id/missing", "/id/"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestHandleSessionConditionalRequests(t *testing.T) {
+	sessionsDir := t.TempDir()
+	modTime := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", modTime)
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected ETag header")
+	}
+	lastModified := server.idx.LastUpdated().UTC().Truncate(time.Second)
+	if got := rec.Header().Get("Last-Modified"); got != lastModified.Format(http.TimeFormat) {
+		t.Fatalf("expected Last-Modified from the last index refresh, got %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected empty 304 for matching ETag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil)
+	req.Header.Set("If-Modified-Since", lastModified.Add(time.Minute).Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for If-Modified-Since, got %d", rec.Code)
+	}
+
+	// A rescan can change the neighbour links, so it invalidates the page.
+	time.Sleep(time.Millisecond)
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a rescan to change the page ETag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil)
+	req.Header.Set("If-None-Match", `W/"stale"`)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for stale ETag, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	rawETag := rec.Header().Get("ETag")
+	req = httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil)
+	req.Header.Set("If-None-Match", rawETag)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rawETag == "" || rec.Code != http.StatusNotModified {
+		t.Fatalf("expected raw download to honor its ETag, got %d (etag %q)", rec.Code, rawETag)
+	}
+}
+
+func TestHandleRawRanges(t *testing.T) {
+	sessionsDir := t.TempDir()
+	modTime := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	fullPath := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", modTime)
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != string(data) {
+		t.Fatalf("expected full download, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(len(data)) {
+		t.Fatalf("expected Content-Length %d, got %q", len(data), got)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != modTime.Format(http.TimeFormat) {
+		t.Fatalf("expected indexed modtime as Last-Modified, got %q", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil)
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", modTime.Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != string(data[10:]) {
+		t.Fatalf("expected 206 with the remaining bytes, got %d", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Range"), fmt.Sprintf("bytes 10-%d/%d", len(data)-1, len(data)); got != want {
+		t.Fatalf("expected Content-Range %q, got %q", want, got)
+	}
+
+	// Through Gzip, as served by default: the download stays uncompressed with
+	// its Content-Length, and If-Range with the ETag resumes it.
+	handler := Gzip(server)
+	req = httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != string(data) {
+		t.Fatalf("expected an uncompressed download through Gzip, got %d (%q)", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(len(data)) {
+		t.Fatalf("expected Content-Length %d through Gzip, got %q", len(data), got)
+	}
+	etag := rec.Header().Get("ETag")
+	if strings.HasPrefix(etag, "W/") {
+		t.Fatalf("expected a strong ETag, got %q", etag)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/raw/2026/01/09/a.jsonl", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != string(data[10:]) {
+		t.Fatalf("expected If-Range with the ETag to resume, got %d", rec.Code)
+	}
+
+	for _, target := range []string{"/raw/2026/01/09/..%2fa.jsonl", "/raw/2026/01/09/../09/a.jsonl", "/raw/2026/01/aa/a.jsonl", "/raw/2026/01/09/missing.jsonl"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestSessionTitle(t *testing.T) {
+	date, _ := sessions.ParseDate("2026", "01", "09")
+	cases := []struct {
+		cwd  string
+		want string
+	}{
+		{"/home/me/proj/", "proj · 2026-01-09 - Codex Session"},
+		{`C:\Users\me\repo`, "repo · 2026-01-09 - Codex Session"},
+		{"/", "/ · 2026-01-09 - Codex Session"},
+		{sessions.UnknownCwd, "a.jsonl · 2026-01-09 - Codex Session"},
+	}
+	for _, tc := range cases {
+		if got := sessionTitle(tc.cwd, date, "a.jsonl"); got != tc.want {
+			t.Fatalf("sessionTitle(%q) = %q, want %q", tc.cwd, got, tc.want)
+		}
+	}
+
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/work/api", time.Now())
+	server := newTestServer(t, sessionsDir)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	if !strings.Contains(rec.Body.String(), "<title>api · 2026-01-09 - Codex Session</title>") {
+		t.Fatalf("expected the cwd in the page title")
+	}
+}
+
+func TestSessionPageListsCwds(t *testing.T) {
+	sessionsDir := t.TempDir()
+	path := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/work/api", time.Now())
+	server := newTestServer(t, sessionsDir)
+	get := func() string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+		return rec.Body.String()
+	}
+	if strings.Contains(get(), `class="meta session-cwds"`) {
+		t.Fatalf("expected no directory list for a single cwd")
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = f.WriteString("{\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"<environment_context>\\n  <cwd>/work/web</cwd>\\n</environment_context>\"}]}}\n")
+	f.Close()
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := server.Rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if body := get(); !strings.Contains(body, "Directories: /work/api, /work/web</p>") {
+		t.Fatalf("expected both cwds in the header")
+	}
+}
+
+func TestParseSessionKey(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"2026-01-09/a.jsonl", "2026/01/09/a.jsonl", true},
+		{"2026/01/09/a.jsonl", "2026/01/09/a.jsonl", true},
+		{"2026-01-09", "", false},
+		{"2026-01-09/../x", "", false},
+		{"bogus/a.jsonl", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := parseSessionKey(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("parseSessionKey(%q): got (%q, %v) want (%q, %v)", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestHandleMeta(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/meta/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var meta sessions.SessionMeta
+	if err := json.Unmarshal(rec.Body.Bytes(), &meta); err != nil {
+		t.Fatalf("decode meta: %v", err)
+	}
+	if meta.Cwd != "/proj" {
+		t.Fatalf("expected cwd /proj, got %q", meta.Cwd)
+	}
+	var withResume struct {
+		Resume *resumeInfo `json:"resume"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &withResume); err != nil {
+		t.Fatalf("decode resume: %v", err)
+	}
+	if withResume.Resume == nil || withResume.Resume.ID != "a.jsonl" || withResume.Resume.Cwd != "/proj" {
+		t.Fatalf("unexpected resume: %+v", withResume.Resume)
+	}
+
+	for _, target := range []string{"/api/meta/2026/01/09/missing.jsonl", "/api/meta/2026/1/09/a.jsonl", "/api/meta/2026/01/09"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+}
+
+func TestSessionViewsShowGitInfo(t *testing.T) {
+	sessionsDir := t.TempDir()
+	repo := filepath.Join(t.TempDir(), "myrepo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0o644); err != nil {
+		t.Fatalf("write HEAD: %v", err)
+	}
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", repo, time.Now())
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/not/a/repo", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	for _, target := range []string{"/2026/01/09/", "/2026/01/09/a.jsonl", "/dir?cwd=" + repo, "/?view=dir"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "git: myrepo @ main") {
+			t.Fatalf("%s: expected git label in body", target)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/b.jsonl", nil))
+	if strings.Contains(rec.Body.String(), "git:") {
+		t.Fatalf("expected no git label outside a repository")
+	}
+}
+
+func TestSessionItemRoleFlags(t *testing.T) {
+	sessionsDir := t.TempDir()
+	path := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, _ = f.WriteString("{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{}\",\"call_id\":\"c1\"}}\n")
+	f.Close()
+	server := newTestServer(t, sessionsDir)
+
+	view, err := server.buildSessionView([]string{"2026", "01", "09", "a.jsonl"}, 0, false)
+	if err != nil {
+		t.Fatalf("build view: %v", err)
+	}
+	if len(view.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(view.Items))
+	}
+	flags := func(item itemView) [3]bool { return [3]bool{item.IsUser, item.IsAssistant, item.IsTool} }
+	want := [][3]bool{{true, false, false}, {false, true, false}, {false, false, true}}
+	for i, item := range view.Items {
+		if flags(item) != want[i] {
+			t.Fatalf("item %d (%s): unexpected flags %v", i, item.Role, flags(item))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	body := rec.Body.String()
+	for _, class := range []string{"bubble-user", "bubble-assistant", "bubble-tool"} {
+		if !strings.Contains(body, class) {
+			t.Fatalf("expected %s hook in session page", class)
+		}
+	}
+}
+
+func TestDayViewShowsSummary(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `<span class="session-summary">Hello</span>`) {
+		t.Fatalf("expected first user message as summary")
+	}
+}
+
+func TestHandleSearchConfiguredLimits(t *testing.T) {
+	sessionsDir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		writeSessionWithCwd(t, sessionsDir, "2026/01/09", fmt.Sprintf("s%d.jsonl", i), "/proj", time.Now())
+	}
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+	server.SetSearchLimits(2, 3, 4)
+
+	cases := []struct {
+		target string
+		want   int
+	}{
+		{"/search?query=hello", 2},
+		{"/search?query=hello&limit=50", 3},
+		{"/search?query=hell&limit=1", 1},
+		{"/search?query=hel", 0},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+		var response searchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: decode: %v", tc.target, err)
+		}
+		if len(response.Results) != tc.want {
+			t.Fatalf("%s: expected %d results, got %d", tc.target, tc.want, len(response.Results))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?format=html&query=hel", nil))
+	if !strings.Contains(rec.Body.String(), "Type at least 4 characters.") {
+		t.Fatalf("expected the configured minimum on the results page, got %s", rec.Body.String())
+	}
+}
+
+func TestHandleSearchJSONL(t *testing.T) {
+	sessionsDir := t.TempDir()
+	base := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		writeSessionWithCwd(t, sessionsDir, "2026/01/09", fmt.Sprintf("s%d.jsonl", i), "/proj", base.Add(time.Duration(i)*time.Minute))
+	}
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?format=jsonl&query=hello", nil))
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	if !rec.Flushed {
+		t.Fatalf("expected results to be flushed while streaming")
+	}
+	lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", rec.Body.String())
+	}
+	for _, line := range lines {
+		var result search.Result
+		if err := json.Unmarshal([]byte(line), &result); err != nil || result.File == "" {
+			t.Fatalf("line %q: expected a result (%v)", line, err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?format=jsonl&query=nomatch", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty stream, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleSearchOffset(t *testing.T) {
+	sessionsDir := t.TempDir()
+	base := time.Date(2026, 1, 9, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		writeSessionWithCwd(t, sessionsDir, "2026/01/09", fmt.Sprintf("s%d.jsonl", i), "/proj", base.Add(time.Duration(i)*time.Minute))
+	}
+	server := newTestServer(t, sessionsDir)
+	server.search = search.NewIndex()
+	if err := server.search.RefreshFrom(server.idx); err != nil {
+		t.Fatalf("search refresh: %v", err)
+	}
+
+	files := func(target string) []string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var response searchResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: decode: %v", target, err)
+		}
+		var out []string
+		for _, result := range response.Results {
+			out = append(out, result.File)
+		}
+		return out
+	}
+	all := files("/search?query=hello")
+	page := files("/search?query=hello&limit=2&offset=2")
+	if len(all) != 5 || len(page) != 2 || page[0] != all[2] || page[1] != all[3] {
+		t.Fatalf("expected matches 3-4 of %v, got %v", all, page)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/search?format=html&query=hello&limit=2&offset=2", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, "Results 3–4") {
+		t.Fatalf("expected the page range, got %s", body)
+	}
+	if !strings.Contains(body, `href="/search?format=html&amp;limit=2&amp;query=hello"`) || !strings.Contains(body, `limit=2&amp;offset=4`) {
+		t.Fatalf("expected previous and next page links, got %s", body)
+	}
+}
+
+func TestDayViewCliVersionFilter(t *testing.T) {
+	sessionsDir := t.TempDir()
+	fullDir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, version := range map[string]string{"old.jsonl": "0.9.0", "new.jsonl": "0.10.0"} {
+		data := fmt.Sprintf("{\"type\":\"session_meta\",\"payload\":{\"id\":%q,\"cwd\":\"/proj\",\"cli_version\":%q}}\n", name, version)
+		if err := os.WriteFile(filepath.Join(fullDir, name), []byte(data), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	server := newTestServer(t, sessionsDir)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	body := get("/2026/01/09/?version=0.9.0")
+	if !strings.Contains(body, `href="/2026/01/09/old.jsonl"`) || strings.Contains(body, `href="/2026/01/09/new.jsonl"`) {
+		t.Fatalf("expected only the 0.9.0 session")
+	}
+	if !strings.Contains(body, "Older CLI") {
+		t.Fatalf("expected outdated CLI warning")
+	}
+	if !strings.Contains(get("/2026/01/09/new.jsonl"), "CLI 0.10.0") {
+		t.Fatalf("expected CLI version on the session page")
+	}
+	if strings.Contains(get("/2026/01/09/new.jsonl"), "Older CLI") {
+		t.Fatalf("newest version should not be flagged")
+	}
+}
+
+func TestDayViewSessionKindFilter(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "qa.jsonl", "/proj", now)
+	codingPath := writeSessionWithCwd(t, sessionsDir, "2026/01/09", "coding.jsonl", "/proj", now)
+	call := "{\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{}\",\"call_id\":\"c1\"}}\n"
+	f, err := os.OpenFile(codingPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.WriteString(call + call); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	f.Close()
+	server := newTestServer(t, sessionsDir)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	body := get("/2026/01/09/")
+	if !strings.Contains(body, `tag-kind-coding">Coding</span>`) || !strings.Contains(body, `tag-kind-qa">Q&amp;A</span>`) {
+		t.Fatalf("expected kind badges in the listing")
+	}
+	if !strings.Contains(body, `href="/2026/01/09/?type=coding">Coding (1)</a>`) {
+		t.Fatalf("expected a coding filter tab")
+	}
+	body = get("/2026/01/09/?type=coding&cwd=/proj")
+	if !strings.Contains(body, `href="/2026/01/09/coding.jsonl"`) || strings.Contains(body, `href="/2026/01/09/qa.jsonl"`) {
+		t.Fatalf("expected only the coding session")
+	}
+	if !strings.Contains(body, `href="/2026/01/09/?cwd=%2Fproj&amp;type=qa"`) {
+		t.Fatalf("expected type tabs to keep the cwd filter")
+	}
+	if body := get("/2026/01/09/?type=bogus"); !strings.Contains(body, `href="/2026/01/09/qa.jsonl"`) || !strings.Contains(body, `href="/2026/01/09/coding.jsonl"`) {
+		t.Fatalf("expected an unknown type to be ignored")
+	}
+}
+
+func TestDayViewMultipleCwdFilter(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/one", now)
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "b.jsonl", "/two", now)
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "c.jsonl", "/three", now)
+	server := newTestServer(t, sessionsDir)
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+	shown := func(body string) []string {
+		var names []string
+		for _, name := range []string{"a.jsonl", "b.jsonl", "c.jsonl"} {
+			if strings.Contains(body, `href="/2026/01/09/`+name+`"`) {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	single := get("/2026/01/09/?cwd=/one")
+	if got := shown(single); len(got) != 1 || got[0] != "a.jsonl" {
+		t.Fatalf("expected only a.jsonl for one cwd, got %v", got)
+	}
+	if !strings.Contains(single, "View directory dates") {
+		t.Fatalf("expected the single-directory links to stay")
+	}
+	// The toggle for /two adds it to the current selection.
+	if !strings.Contains(single, `href="/2026/01/09/?cwd=%2Fone&amp;cwd=%2Ftwo"`) {
+		t.Fatalf("expected an add toggle keeping /one selected")
+	}
+
+	multi := get("/2026/01/09/?cwd=/one&cwd=/two&cwd=/one")
+	if got := shown(multi); len(got) != 2 || got[0] != "a.jsonl" || got[1] != "b.jsonl" {
+		t.Fatalf("expected the union of /one and /two, got %v", got)
+	}
+	if !strings.Contains(multi, "2 directories") || strings.Contains(multi, "View directory dates") {
+		t.Fatalf("expected a multi-directory label without the single-directory link")
+	}
+	if !strings.Contains(multi, `href="/2026/01/09/?cwd=%2Ftwo"`) {
+		t.Fatalf("expected a remove toggle for /one")
+	}
+}
+
+func TestSessionPageTruncatesLongSessions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	fullDir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	var data strings.Builder
+	for i := 1; i <= 10; i++ {
+		role, kind := "user", "input_text"
+		if i%2 == 0 {
+			role, kind = "assistant", "output_text"
+		}
+		fmt.Fprintf(&data, "{\"timestamp\":\"2026-01-09T01:00:%02dZ\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":%q,\"content\":[{\"type\":%q,\"text\":\"msg-%d\"}]}}\n", i, role, kind, i)
+	}
+	if err := os.WriteFile(filepath.Join(fullDir, "long.jsonl"), []byte(data.String()), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+	server.SetTruncateItems(3)
+
+	view, err := server.buildSessionView([]string{"2026", "01", "09", "long.jsonl"}, 3, false)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if len(view.Items) != 6 || view.HiddenItems != 4 || view.HiddenAt != 3 {
+		t.Fatalf("expected 6 items with 4 hidden at 3, got %d items, %d hidden at %d", len(view.Items), view.HiddenItems, view.HiddenAt)
+	}
+	if view.Items[2].Content != "msg-3" || view.Items[3].Content != "msg-8" {
+		t.Fatalf("expected head and tail around the gap, got %q then %q", view.Items[2].Content, view.Items[3].Content)
+	}
+	if view.LastUserLine != 9 {
+		t.Fatalf("expected last user line 9, got %d", view.LastUserLine)
+	}
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/long.jsonl", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "4 items hidden") || strings.Contains(body, `id="line-5"`) {
+		t.Fatalf("expected the truncated page with a hidden-items marker")
+	}
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/long.jsonl?full=1", nil))
+	if body := rec.Body.String(); strings.Contains(body, "items hidden") || !strings.Contains(body, `id="line-5"`) {
+		t.Fatalf("expected every item with full=1")
+	}
+}
+
+func TestSessionOrderDesc(t *testing.T) {
+	sessionsDir := t.TempDir()
+	fullDir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	var data strings.Builder
+	for i := 1; i <= 10; i++ {
+		role, kind := "user", "input_text"
+		if i%2 == 0 {
+			role, kind = "assistant", "output_text"
+		}
+		fmt.Fprintf(&data, "{\"timestamp\":\"2026-01-09T01:00:%02dZ\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":%q,\"content\":[{\"type\":%q,\"text\":\"msg-%d\"}]}}\n", i, role, kind, i)
+	}
+	if err := os.WriteFile(filepath.Join(fullDir, "long.jsonl"), []byte(data.String()), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	view, err := server.buildSessionView([]string{"2026", "01", "09", "long.jsonl"}, 3, false)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	markdown := view.AllMarkdown
+	view.reverseItems()
+	if view.Items[0].Content != "msg-10" || view.Items[2].Content != "msg-8" || view.Items[3].Content != "msg-3" || view.HiddenAt != 3 {
+		t.Fatalf("expected newest first with the gap kept, got %q..%q|%q at %d", view.Items[0].Content, view.Items[2].Content, view.Items[3].Content, view.HiddenAt)
+	}
+	if view.AllMarkdown != markdown {
+		t.Fatalf("expected the Markdown copy to stay chronological")
+	}
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+	body := get("/2026/01/09/long.jsonl")
+	if strings.Index(body, `id="line-1"`) > strings.Index(body, `id="line-10"`) || !strings.Contains(body, `<a href="?order=desc">Newest first</a>`) {
+		t.Fatalf("expected chronological order by default with a newest-first link")
+	}
+	body = get("/2026/01/09/long.jsonl?order=desc&full=1")
+	if strings.Index(body, `id="line-10"`) > strings.Index(body, `id="line-1"`) {
+		t.Fatalf("expected newest first with order=desc")
+	}
+	if !strings.Contains(body, `<a href="?full=1">Oldest first</a>`) {
+		t.Fatalf("expected an oldest-first link keeping full=1")
+	}
+}
+
+func TestSessionChatView(t *testing.T) {
+	sessionsDir := t.TempDir()
+	fullDir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data := "{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"List files\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"reasoning\",\"summary\":[{\"type\":\"summary_text\",\"text\":\"Run ls\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call\",\"name\":\"shell\",\"arguments\":\"{\\\"command\\\":[\\\"ls\\\"]}\",\"call_id\":\"c1\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"function_call_output\",\"call_id\":\"c1\",\"output\":\"README.md\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:05Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Just a README\"}]}}\n"
+	if err := os.WriteFile(filepath.Join(fullDir, "a.jsonl"), []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	view, err := server.buildSessionView([]string{"2026", "01", "09", "a.jsonl"}, 0, true)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if len(view.Items) != 3 {
+		t.Fatalf("expected user, reasoning and agent items, got %d", len(view.Items))
+	}
+	for _, item := range view.Items {
+		if item.IsTool {
+			t.Fatalf("expected no tool items in the chat view, got line %d", item.Line)
+		}
+	}
+
+	get := func(target string) string {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+	body := get("/2026/01/09/a.jsonl")
+	if !strings.Contains(body, `id="line-3"`) || !strings.Contains(body, `<a href="?view=chat">Chat only</a>`) {
+		t.Fatalf("expected tool calls by default with a chat-only link")
+	}
+	body = get("/2026/01/09/a.jsonl?view=chat&order=desc")
+	if strings.Contains(body, `id="line-3"`) || !strings.Contains(body, `id="line-5"`) {
+		t.Fatalf("expected the chat view to drop tool items")
+	}
+	if !strings.Contains(body, `<a href="?order=desc">Show tool calls</a>`) || !strings.Contains(body, `<a href="?view=chat">Oldest first</a>`) {
+		t.Fatalf("expected toggles keeping the other option")
+	}
+}
+
+func TestSessionPrintView(t *testing.T) {
+	sessionsDir := t.TempDir()
+	fullDir := filepath.Join(sessionsDir, "2026", "01", "09")
+	if err := os.MkdirAll(fullDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	data := "{\"timestamp\":\"2026-01-09T01:00:00Z\",\"type\":\"session_meta\",\"payload\":{\"id\":\"abc\",\"cwd\":\"/tmp\"}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:01Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Hello\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:02Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"reasoning\",\"summary\":[{\"type\":\"summary_text\",\"text\":\"Thinking it over\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:03Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"assistant\",\"content\":[{\"type\":\"output_text\",\"text\":\"Hi\"}]}}\n" +
+		"{\"timestamp\":\"2026-01-09T01:00:04Z\",\"type\":\"response_item\",\"payload\":{\"type\":\"message\",\"role\":\"user\",\"content\":[{\"type\":\"input_text\",\"text\":\"Bye\"}]}}\n"
+	if err := os.WriteFile(filepath.Join(fullDir, "a.jsonl"), []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+	server.SetTruncateItems(1)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `<a href="?print=1">Print view</a>`) || strings.Contains(body, `class="print-view"`) {
+		t.Fatalf("expected the normal page to link to the print view")
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl?print=1", nil))
+	body = rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `<body class="print-view">`) {
+		t.Fatalf("expected the print layout, got %d", rec.Code)
+	}
+	for _, unwanted := range []string{`<header class="sticky-header">`, `action="/share/`, "data-copy-id=", "<script>", "items hidden"} {
+		if strings.Contains(body, unwanted) {
+			t.Fatalf("expected print view without %q", unwanted)
+		}
+	}
+	if !strings.Contains(body, "<details open>") || !strings.Contains(body, `id="line-5"`) {
+		t.Fatalf("expected expanded reasoning and every item")
+	}
+}
+
+func TestThemeColorsOverrideTheme(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	if strings.Contains(rec.Body.String(), "<style>body.theme-") {
+		t.Fatalf("expected no override block without theme colors")
+	}
+
+	server.SetThemeColors("#fafafa", "", "rgb(1, 2, 3)")
+	for _, target := range []string{"/", "/2026/01/09/", "/2026/01/09/a.jsonl"} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", target, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), "<style>body.theme-graphite-teal { --ink: #fafafa; --accent: rgb(1, 2, 3); }</style>") {
+			t.Fatalf("%s: expected the theme override block", target)
+		}
+	}
+}
+
+func TestHandleInstructions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "plain.jsonl", "/proj", time.Now())
+	withInstructions := filepath.Join(sessionsDir, "2026", "01", "09", "prompt.jsonl")
+	data := "{\"type\":\"session_meta\",\"payload\":{\"id\":\"p\",\"instructions\":\"# Rules\\n\\n- be terse\"}}\n"
+	if err := os.WriteFile(withInstructions, []byte(data), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/instructions/2026/01/09/prompt.jsonl", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("expected markdown, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Body.String() != "# Rules\n\n- be terse" {
+		t.Fatalf("unexpected instructions %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/instructions/2026/01/09/plain.jsonl", nil))
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("expected 204 without instructions, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/instructions/2026/01/09/missing.jsonl", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown session, got %d", rec.Code)
+	}
+}
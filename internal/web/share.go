@@ -1,33 +1,74 @@
 package web
 
 import (
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 )
 
-// NewShareServer serves only exact filenames from the share directory.
-func NewShareServer(shareDir string) http.Handler {
+const shareRobotsTxt = "User-agent: *\nDisallow: /\n"
+
+// NewShareServer serves only exact filenames from the share directory, adding
+// csp as the Content-Security-Policy of .html files unless it is empty.
+func NewShareServer(shareDir, csp string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet && r.Method != http.MethodHead {
 			http.NotFound(w, r)
 			return
 		}
 
+		// Shares are often reachable through a public funnel; keep crawlers out.
+		w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+
 		path := strings.TrimPrefix(r.URL.Path, "/")
-		if path == "" || strings.Contains(path, "/") || strings.Contains(path, "\\") || strings.Contains(path, "..") {
-			http.NotFound(w, r)
+		if path == "robots.txt" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			_, _ = io.WriteString(w, shareRobotsTxt)
 			return
 		}
+		serveShareFile(w, r, shareDir, path, csp)
+	})
+}
 
-		target := filepath.Join(shareDir, path)
-		info, err := os.Stat(target)
-		if err != nil || info.IsDir() {
-			http.NotFound(w, r)
-			return
-		}
+// serveShareFile serves name from shareDir, refusing anything but a plain
+// file name. It backs both NewShareServer and /shared/ in single-port mode.
+func serveShareFile(w http.ResponseWriter, r *http.Request, shareDir, name, csp string) {
+	if name == "" || strings.Contains(name, "/") || strings.Contains(name, "\\") || strings.Contains(name, "..") {
+		http.NotFound(w, r)
+		return
+	}
 
-		http.ServeFile(w, r, target)
-	})
+	target := filepath.Join(shareDir, name)
+	info, err := os.Stat(target)
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if csp != "" && strings.EqualFold(filepath.Ext(name), ".html") {
+		w.Header().Set("Content-Security-Policy", csp)
+	}
+	http.ServeFile(w, r, target)
+}
+
+// sharedPathPrefix is where the main server serves shares under -single-port.
+const sharedPathPrefix = "/shared/"
+
+// EnableSinglePortShares serves share files from the main server under
+// /shared/ and makes share URLs same-origin, for setups without a second port.
+// csp is applied as in NewShareServer.
+func (s *Server) EnableSinglePortShares(csp string) {
+	s.singlePortShares = true
+	s.shareCSP = csp
+}
+
+func (s *Server) handleShared(w http.ResponseWriter, r *http.Request, name string) {
+	if !s.singlePortShares || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	serveShareFile(w, r, s.shareDir, name, s.shareCSP)
 }
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"codex-manager/internal/sessions"
+)
+
+type shareDayItemView struct {
+	Name    string
+	Href    string
+	Summary string
+	Cwd     string
+	ModTime string
+}
+
+type shareDayView struct {
+	Date       dateView
+	Items      []shareDayItemView
+	Skipped    int
+	ThemeClass string
+	ThemeVars  template.CSS
+}
+
+// handleShareDay shares every session of a day plus an index page linking
+// them, and returns the index URL. Empty and too-large sessions are skipped
+// and not charged. Every file published costs a -share-rate token, and
+// nothing is published unless all sessions render.
+func (s *Server) handleShareDay(w http.ResponseWriter, r *http.Request, parts []string) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	date, ok := sessions.ParseDate(parts[0], parts[1], parts[2])
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	files := s.idx.SessionsByDate(date)
+	if len(files) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	view := shareDayView{
+		Date:       dateView{Label: date.String(), Path: date.Path(), Count: len(files)},
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+	}
+	shared := make([]sessions.SessionFile, 0, len(files))
+	for _, file := range files {
+		if file.Empty || (s.maxParseSize > 0 && file.Size > s.maxParseSize) {
+			view.Skipped++
+			continue
+		}
+		shared = append(shared, file)
+	}
+	// One token per session share plus the index. A day costing more than a
+	// full bucket would get 429 forever, so it is refused outright.
+	if s.shareLimit != nil {
+		cost := len(shared) + 1
+		if limit := s.shareLimit.capacity(); cost > limit {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("sharing this day publishes %d pages, more than -share-rate allows per minute (%d); raise -share-rate", cost, limit))
+			return
+		}
+		if !s.shareLimit.allowN(clientIP(r.RemoteAddr), cost) {
+			w.Header().Set("Retry-After", "60")
+			writeJSONError(w, http.StatusTooManyRequests, "too many share requests; try again later")
+			return
+		}
+	}
+	pages := make([][]byte, 0, len(shared))
+	rendered := shared[:0]
+	for _, file := range shared {
+		// A slot per page, so a long day does not hold one for its whole
+		// render while single shares wait behind it.
+		release, ok := s.acquireShareSlot(r)
+		if !ok {
+			writeShareBusy(w)
+			return
+		}
+		html, err := s.renderShareHTML([]string{parts[0], parts[1], parts[2], file.Name})
+		release()
+		if errors.Is(err, errSessionTooLarge) {
+			view.Skipped++
+			continue
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		pages = append(pages, html)
+		rendered = append(rendered, file)
+	}
+
+	var published []string
+	fail := func(err error) {
+		s.removeShareFiles(published)
+		writeShareError(w, err)
+	}
+	for i, file := range rendered {
+		shareURL, fileName, err := s.publishShare(r, pages[i])
+		if err != nil {
+			fail(err)
+			return
+		}
+		published = append(published, fileName)
+		// Local shares sit next to the index, so a relative link keeps working
+		// whichever host the index is opened from.
+		href := fileName
+		if href == "" {
+			href = shareURL
+		}
+		view.Items = append(view.Items, shareDayItemView{
+			Name:    file.Name,
+			Href:    href,
+			Summary: file.Summary,
+			Cwd:     displayCwd(sessions.CwdForFile(file)),
+			ModTime: s.formatTime(file.ModTime),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := s.renderer.Execute(&buf, "share-day", view); err != nil {
+		s.removeShareFiles(published)
+		http.Error(w, "failed to render html: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	indexURL, _, err := s.publishShare(r, buf.Bytes())
+	if err != nil {
+		fail(err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"url": indexURL, "sessions": len(view.Items), "skipped": view.Skipped})
+}
+
+// removeShareFiles deletes local shares published before a day share failed.
+// htmlbucket uploads (empty names) cannot be taken back.
+func (s *Server) removeShareFiles(names []string) {
+	for _, name := range names {
+		if name != "" {
+			_ = os.Remove(filepath.Join(s.shareDir, name))
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"archive/zip"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type shareFileView struct {
+	Name    string
+	URL     string
+	Size    string
+	Created string
+}
+
+type sharesPageView struct {
+	Shares     []shareFileView
+	ShareDir   string
+	HTMLBucket bool
+	ThemeClass string
+	ThemeVars  template.CSS
+	ReadOnly   bool
+}
+
+type shareFile struct {
+	name string
+	path string
+	info os.FileInfo
+}
+
+// listShareFiles returns generated share files in shareDir, newest first.
+// A missing directory simply means nothing has been shared yet.
+func (s *Server) listShareFiles() ([]shareFile, error) {
+	entries, err := os.ReadDir(s.shareDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make([]shareFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isShareFilename(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, shareFile{
+			name: entry.Name(),
+			path: filepath.Join(s.shareDir, entry.Name()),
+			info: info,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].info.ModTime().Equal(files[j].info.ModTime()) {
+			return files[i].name < files[j].name
+		}
+		return files[i].info.ModTime().After(files[j].info.ModTime())
+	})
+	return files, nil
+}
+
+func isShareFilename(name string) bool {
+	return safeFilename(name) && strings.HasSuffix(name, ".html")
+}
+
+func (s *Server) handleShares(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	files, err := s.listShareFiles()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list shares: %v", err), http.StatusInternalServerError)
+		return
+	}
+	views := make([]shareFileView, 0, len(files))
+	for _, file := range files {
+		views = append(views, shareFileView{
+			Name:    file.name,
+			URL:     s.buildShareURL(r, file.name),
+			Size:    formatBytes(file.info.Size()),
+			Created: s.formatTime(file.info.ModTime()),
+		})
+	}
+	view := sharesPageView{
+		Shares:     views,
+		ShareDir:   s.shareDir,
+		HTMLBucket: s.htmlBucket != nil,
+		ThemeClass: s.themeClass,
+		ThemeVars:  s.themeVars,
+		ReadOnly:   s.readOnly,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = s.renderer.Execute(w, "shares", view)
+}
+
+func (s *Server) handleRevokeShare(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	if !isShareFilename(name) {
+		http.NotFound(w, r)
+		return
+	}
+	if err := os.Remove(filepath.Join(s.shareDir, name)); err != nil {
+		if os.IsNotExist(err) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, fmt.Sprintf("failed to revoke share: %v", err), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/shares", http.StatusSeeOther)
+}
+
+func (s *Server) handleSharesZip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	files, err := s.listShareFiles()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list shares: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="codex-shares.zip"`)
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		if err := addZipFile(zw, file); err != nil {
+			// Headers are already sent; a truncated archive is the best signal left.
+			return
+		}
+	}
+	_ = zw.Close()
+}
+
+func addZipFile(zw *zip.Writer, file shareFile) error {
+	src, err := os.Open(file.path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	header := &zip.FileHeader{
+		Name:     file.name,
+		Method:   zip.Deflate,
+		Modified: file.info.ModTime(),
+	}
+	dst, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,128 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeShareFile(t *testing.T, shareDir, name, body string) {
+	t.Helper()
+	if err := os.MkdirAll(shareDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(shareDir, name), []byte(body), 0o644); err != nil {
+		t.Fatalf("write share: %v", err)
+	}
+}
+
+func TestSharesPageListsFiles(t *testing.T) {
+	server := newTestServer(t, t.TempDir())
+	writeShareFile(t, server.shareDir, "abc.html", "<p>one</p>")
+	writeShareFile(t, server.shareDir, "notes.txt", "ignored")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shares", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "abc.html") || !strings.Contains(body, "/shares/revoke/abc.html") {
+		t.Fatalf("expected share row, got %q", body)
+	}
+	if strings.Contains(body, "notes.txt") {
+		t.Fatalf("expected non-share files to be skipped")
+	}
+}
+
+func TestSharesPageMissingDir(t *testing.T) {
+	server := newTestServer(t, t.TempDir())
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shares", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "No local shares yet.") {
+		t.Fatalf("expected empty state")
+	}
+}
+
+func TestRevokeShare(t *testing.T) {
+	server := newTestServer(t, t.TempDir())
+	writeShareFile(t, server.shareDir, "abc.html", "<p>one</p>")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shares/revoke/abc.html", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected GET revoke to 404, got %d", rec.Code)
+	}
+
+	for _, header := range [][2]string{{"Origin", "https://evil.example"}, {"Sec-Fetch-Site", "same-site"}} {
+		req := httptest.NewRequest(http.MethodPost, "/shares/revoke/abc.html", nil)
+		req.Header.Set(header[0], header[1])
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403, got %d", header[0], header[1], rec.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(server.shareDir, "abc.html")); err != nil {
+		t.Fatalf("expected a cross-site revoke to keep the share: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/shares/revoke/abc.html", nil)
+	req.Header.Set("Origin", "http://example.com")
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d", rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(server.shareDir, "abc.html")); !os.IsNotExist(err) {
+		t.Fatalf("expected share to be removed, got %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shares/revoke/..%2Fsecret.html", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected traversal to 404, got %d", rec.Code)
+	}
+}
+
+func TestSharesZip(t *testing.T) {
+	server := newTestServer(t, t.TempDir())
+	writeShareFile(t, server.shareDir, "a.html", "alpha")
+	writeShareFile(t, server.shareDir, "b.html", "beta")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/shares/download.zip", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/zip" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	data := rec.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(b)
+	}
+	if contents["a.html"] != "alpha" || contents["b.html"] != "beta" || len(contents) != 2 {
+		t.Fatalf("unexpected zip contents: %v", contents)
+	}
+}
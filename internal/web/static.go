@@ -0,0 +1,31 @@
+package web
+
+import (
+	"net/http"
+
+	"codex-manager/internal/render"
+)
+
+// appAssets maps the embedded browser assets served at the root of the main
+// UI to their content types.
+var appAssets = map[string]string{
+	"favicon.ico":          "image/x-icon",
+	"icon.svg":             "image/svg+xml",
+	"manifest.webmanifest": "application/manifest+json",
+}
+
+func (s *Server) handleAppAsset(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.NotFound(w, r)
+		return
+	}
+	data, ok := render.Static(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", appAssets[name])
+	// The assets only change with the binary.
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppAssets(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/x-icon" {
+		t.Fatalf("favicon: got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(rec.Body.Bytes(), []byte{0, 0, 1, 0}) {
+		t.Fatalf("expected an ICO header, got % x", rec.Body.Bytes()[:4])
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/manifest.webmanifest", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/manifest+json" {
+		t.Fatalf("manifest: got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var manifest struct {
+		StartURL string `json:"start_url"`
+		Display  string `json:"display"`
+		Icons    []struct {
+			Src string `json:"src"`
+		} `json:"icons"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &manifest); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if manifest.StartURL != "/" || manifest.Display != "standalone" || len(manifest.Icons) == 0 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	for _, icon := range manifest.Icons {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, icon.Src, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("manifest icon %s: got %d", icon.Src, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/favicon.ico", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for POST, got %d", rec.Code)
+	}
+
+	// Pages link the manifest; shares, viewed on another host, do not.
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/a.jsonl", nil))
+	if !strings.Contains(rec.Body.String(), `rel="manifest"`) {
+		t.Fatalf("expected the session page to link the manifest")
+	}
+	html, err := server.renderShareHTML([]string{"2026", "01", "09", "a.jsonl"})
+	if err != nil {
+		t.Fatalf("render share: %v", err)
+	}
+	if strings.Contains(string(html), `rel="manifest"`) {
+		t.Fatalf("expected share HTML without the manifest link")
+	}
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"codex-manager/internal/sessions"
+)
+
+type sessionTypeCount struct {
+	Type    string `json:"type"`
+	Subtype string `json:"subtype,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// sessionStats is the /api/session-stats response: how the file's lines break
+// down by envelope type, next to the number of items the session view renders.
+type sessionStats struct {
+	Lines         int                `json:"lines"`
+	Items         int                `json:"items"`
+	UnparsedLines int                `json:"unparsedLines"`
+	Types         []sessionTypeCount `json:"types"`
+}
+
+// typeHistogram flattens counts, most frequent first and then by name.
+func typeHistogram(counts map[sessions.EnvelopeType]int) ([]sessionTypeCount, int) {
+	out := make([]sessionTypeCount, 0, len(counts))
+	total := 0
+	for kind, count := range counts {
+		out = append(out, sessionTypeCount{Type: kind.Type, Subtype: kind.Subtype, Count: count})
+		total += count
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].Type != out[j].Type {
+			return out[i].Type < out[j].Type
+		}
+		return out[i].Subtype < out[j].Subtype
+	})
+	return out, total
+}
+
+func (s *Server) handleSessionStats(w http.ResponseWriter, r *http.Request, statsPath string) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	file, ok := s.lookupFilePath(statsPath)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if s.maxParseSize > 0 && file.Size > s.maxParseSize {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, errSessionTooLarge.Error())
+		return
+	}
+	session, err := sessions.ParseSessionCached(file.Path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "failed to parse session")
+		return
+	}
+	types, lines := typeHistogram(session.TypeCounts)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sessionStats{
+		Lines:         lines,
+		Items:         len(session.Items),
+		UnparsedLines: session.UnparsedLines,
+		Types:         types,
+	})
+}
@@ -0,0 +1,38 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleSessionStats(t *testing.T) {
+	sessionsDir := t.TempDir()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "a.jsonl", "/proj", time.Now())
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/session-stats/2026/01/09/a.jsonl", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats sessionStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if stats.Lines != 2 || stats.Items != 1 || len(stats.Types) != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// Equal counts fall back to name order.
+	if stats.Types[0].Type != "response_item" || stats.Types[0].Subtype != "message" || stats.Types[1].Type != "session_meta" {
+		t.Fatalf("unexpected type order: %+v", stats.Types)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/session-stats/2026/01/09/missing.jsonl", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown session, got %d", rec.Code)
+	}
+}
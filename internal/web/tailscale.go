@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,10 +10,30 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// SetupTailscale configures tailscale serve/funnel for the share server.
-func SetupTailscale(shareAddr string) (string, error) {
+// Distinct setup failures, so callers can tell the user what to fix.
+var (
+	ErrTailscaleNotInstalled = errors.New("tailscale binary not found")
+	ErrTailscaleNotLoggedIn  = errors.New("tailscale is not logged in")
+	ErrTailscaleNoFunnel     = errors.New("tailscale funnel is not enabled for this node")
+	ErrTailscaleNotReady     = errors.New("tailscale did not report a DNS name")
+	ErrTailscaleTimeout      = errors.New("tailscale command timed out")
+)
+
+const tailscaleHostAttempts = 5
+
+// tailscaleRetryDelay is the first backoff between status polls; it doubles each attempt.
+var tailscaleRetryDelay = 500 * time.Millisecond
+
+// tailscaleWaitDelay bounds how long a killed command may keep its output
+// pipe open (e.g. through a child process) before Wait gives up.
+const tailscaleWaitDelay = time.Second
+
+// SetupTailscale configures tailscale serve/funnel for the share server. Each
+// tailscale invocation is killed after timeout, failing with ErrTailscaleTimeout.
+func SetupTailscale(shareAddr string, timeout time.Duration) (string, error) {
 	binary, err := detectTailscale()
 	if err != nil {
 		return "", err
@@ -22,22 +43,67 @@ func SetupTailscale(shareAddr string) (string, error) {
 		return "", err
 	}
 
-	if err := runTailscale(binary, "serve", "--bg", "--yes", "--http", port); err != nil {
+	for _, args := range tailscaleSetupArgs(port) {
+		if err := runTailscale(binary, timeout, args...); err != nil {
+			return "", err
+		}
+	}
+
+	host, err := waitForTailscaleHost(binary, timeout)
+	if err != nil {
 		return "", err
 	}
-	if err := runTailscale(binary, "funnel", "--bg", "--yes", port); err != nil {
+	return strings.TrimSuffix(host, "."), nil
+}
+
+// SetupTailscaleDryRun logs the serve/funnel/status commands SetupTailscale
+// would run without changing the tailnet. Only the read-only status query is
+// executed, to report the host share URLs would use.
+func SetupTailscaleDryRun(shareAddr string, timeout time.Duration, logf func(format string, args ...any)) (string, error) {
+	binary, err := detectTailscale()
+	if err != nil {
+		return "", err
+	}
+	port, err := sharePort(shareAddr)
+	if err != nil {
 		return "", err
 	}
 
-	host, err := tailscaleHost(binary)
+	for _, args := range tailscaleSetupArgs(port) {
+		logf("tailscale dry run: would run %s", formatCommand(binary, args))
+	}
+	logf("tailscale dry run: running %s", formatCommand(binary, tailscaleStatusArgs))
+	host, err := waitForTailscaleHost(binary, timeout)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSuffix(host, "."), nil
 }
 
+var tailscaleStatusArgs = []string{"status", "--json"}
+
+// tailscaleSetupArgs lists the mutating commands that expose the share port.
+func tailscaleSetupArgs(port string) [][]string {
+	return [][]string{
+		{"serve", "--bg", "--yes", "--http", port},
+		{"funnel", "--bg", "--yes", port},
+	}
+}
+
+func formatCommand(binary string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, part := range append([]string{binary}, args...) {
+		if strings.ContainsAny(part, " \t\"'") {
+			part = strconv.Quote(part)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
 type tailscaleStatus struct {
-	Self struct {
+	BackendState string `json:"BackendState"`
+	Self         struct {
 		DNSName string `json:"DNSName"`
 	} `json:"Self"`
 }
@@ -49,7 +115,7 @@ func detectTailscale() (string, error) {
 	}
 	path, err := exec.LookPath("tailscale")
 	if err != nil {
-		return "", errors.New("tailscale binary not found")
+		return "", ErrTailscaleNotInstalled
 	}
 	return path, nil
 }
@@ -79,27 +145,91 @@ func sharePort(shareAddr string) (string, error) {
 	return port, nil
 }
 
-func runTailscale(binary string, args ...string) error {
-	cmd := exec.Command(binary, args...)
+// tailscaleOutput runs one tailscale command, killing it once timeout passes.
+func tailscaleOutput(binary string, timeout time.Duration, args []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.WaitDelay = tailscaleWaitDelay
 	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("%w after %s: %s", ErrTailscaleTimeout, timeout, formatCommand(binary, args))
+	}
+	return output, err
+}
+
+func runTailscale(binary string, timeout time.Duration, args ...string) error {
+	output, err := tailscaleOutput(binary, timeout, args)
+	if errors.Is(err, ErrTailscaleTimeout) {
+		return err
+	}
 	if err != nil {
-		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+		text := strings.TrimSpace(string(output))
+		if classified := classifyTailscaleOutput(text); classified != nil {
+			return fmt.Errorf("%w: %s", classified, text)
+		}
+		return fmt.Errorf("%v: %s", err, text)
 	}
 	return nil
 }
 
-func tailscaleHost(binary string) (string, error) {
-	cmd := exec.Command(binary, "status", "--json")
-	output, err := cmd.CombinedOutput()
+// classifyTailscaleOutput maps known CLI failure messages to sentinel errors.
+func classifyTailscaleOutput(output string) error {
+	lower := strings.ToLower(output)
+	switch {
+	case strings.Contains(lower, "logged out") || strings.Contains(lower, "needslogin") || strings.Contains(lower, "tailscale up"):
+		return ErrTailscaleNotLoggedIn
+	case strings.Contains(lower, "funnel") && (strings.Contains(lower, "not enabled") || strings.Contains(lower, "not available") || strings.Contains(lower, "not allowed")):
+		return ErrTailscaleNoFunnel
+	}
+	return nil
+}
+
+// waitForTailscaleHost polls tailscale status with exponential backoff while
+// the daemon is still starting (e.g. right after boot).
+func waitForTailscaleHost(binary string, timeout time.Duration) (string, error) {
+	delay := tailscaleRetryDelay
+	var lastErr error
+	for attempt := 1; attempt <= tailscaleHostAttempts; attempt++ {
+		host, err := tailscaleHost(binary, timeout)
+		if err == nil {
+			return host, nil
+		}
+		if !errors.Is(err, ErrTailscaleNotReady) {
+			return "", err
+		}
+		lastErr = err
+		if attempt < tailscaleHostAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return "", fmt.Errorf("%w after %d attempts", lastErr, tailscaleHostAttempts)
+}
+
+func tailscaleHost(binary string, timeout time.Duration) (string, error) {
+	output, err := tailscaleOutput(binary, timeout, tailscaleStatusArgs)
+	if errors.Is(err, ErrTailscaleTimeout) {
+		// A hung status call is not worth retrying.
+		return "", err
+	}
 	if err != nil {
-		return "", fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+		text := strings.TrimSpace(string(output))
+		if classified := classifyTailscaleOutput(text); classified != nil {
+			return "", fmt.Errorf("%w: %s", classified, text)
+		}
+		// The daemon may not be accepting connections yet.
+		return "", fmt.Errorf("%w: %v: %s", ErrTailscaleNotReady, err, text)
 	}
 	var status tailscaleStatus
 	if err := json.Unmarshal(output, &status); err != nil {
 		return "", err
 	}
+	if status.BackendState == "NeedsLogin" || status.BackendState == "NeedsMachineAuth" {
+		return "", fmt.Errorf("%w (state %s)", ErrTailscaleNotLoggedIn, status.BackendState)
+	}
 	if status.Self.DNSName == "" {
-		return "", errors.New("tailscale status missing DNSName")
+		return "", ErrTailscaleNotReady
 	}
 	return status.Self.DNSName, nil
 }
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetupTailscaleDryRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script stub")
+	}
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "mutated")
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = status ]; then echo '{\"Self\":{\"DNSName\":\"box.tail.ts.net.\"}}'; exit 0; fi\n" +
+		"touch " + marker + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "tailscale"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write stub: %v", err)
+	}
+	t.Setenv("PATH", dir)
+
+	var logged []string
+	host, err := SetupTailscaleDryRun(":8081", time.Minute, func(format string, args ...any) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if host != "box.tail.ts.net" {
+		t.Fatalf("unexpected host %q", host)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("expected serve/funnel not to run")
+	}
+	joined := strings.Join(logged, "\n")
+	for _, want := range []string{"serve --bg --yes --http 8081", "funnel --bg --yes 8081", "status --json"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected log to mention %q, got:\n%s", want, joined)
+		}
+	}
+}
+
+func writeTailscaleStub(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tailscale"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("write stub: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSetupTailscaleErrors(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("uses a PATH shell script stub")
+	}
+	previous := tailscaleRetryDelay
+	tailscaleRetryDelay = time.Millisecond
+	t.Cleanup(func() { tailscaleRetryDelay = previous })
+
+	cases := []struct {
+		name   string
+		script string
+		want   error
+	}{
+		{
+			name:   "not logged in",
+			script: "if [ \"$1\" = status ]; then echo '{\"BackendState\":\"NeedsLogin\",\"Self\":{}}'; fi\n",
+			want:   ErrTailscaleNotLoggedIn,
+		},
+		{
+			name:   "no funnel",
+			script: "if [ \"$1\" = funnel ]; then echo 'Funnel not available; \"funnel\" node attribute not set.' >&2; exit 1; fi\n",
+			want:   ErrTailscaleNoFunnel,
+		},
+		{
+			name:   "never ready",
+			script: "if [ \"$1\" = status ]; then echo '{\"BackendState\":\"Starting\",\"Self\":{}}'; fi\n",
+			want:   ErrTailscaleNotReady,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			writeTailscaleStub(t, tc.script)
+			_, err := SetupTailscale(":8081", time.Minute)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestSetupTailscaleRetriesUntilReady(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("uses a PATH shell script stub")
+	}
+	previous := tailscaleRetryDelay
+	tailscaleRetryDelay = time.Millisecond
+	t.Cleanup(func() { tailscaleRetryDelay = previous })
+
+	counter := filepath.Join(t.TempDir(), "count")
+	// The first two status calls report no DNS name yet.
+	writeTailscaleStub(t, "if [ \"$1\" = status ]; then\n"+
+		"  echo x >> "+counter+"\n"+
+		"  if [ $(wc -l < "+counter+") -lt 3 ]; then echo '{\"BackendState\":\"Starting\",\"Self\":{}}'; exit 0; fi\n"+
+		"  echo '{\"BackendState\":\"Running\",\"Self\":{\"DNSName\":\"box.tail.ts.net.\"}}'\n"+
+		"fi\n")
+
+	host, err := SetupTailscale(":8081", time.Minute)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if host != "box.tail.ts.net" {
+		t.Fatalf("unexpected host %q", host)
+	}
+}
+
+func TestSetupTailscaleTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("uses a PATH shell script stub")
+	}
+	writeTailscaleStub(t, "if [ \"$1\" = serve ]; then sleep 5; fi\n")
+
+	start := time.Now()
+	_, err := SetupTailscale(":8081", 50*time.Millisecond)
+	if !errors.Is(err, ErrTailscaleTimeout) {
+		t.Fatalf("expected %v, got %v", ErrTailscaleTimeout, err)
+	}
+	if !strings.Contains(err.Error(), "serve --bg") {
+		t.Fatalf("expected the hung command in the error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("expected setup to give up promptly, took %s", elapsed)
+	}
+}
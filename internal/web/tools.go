@@ -0,0 +1,20 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleTools lists tool call counts gathered by the last search reindex.
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	if s.search == nil {
+		writeJSONError(w, http.StatusServiceUnavailable, "search index not available")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.search.Tools())
+}
@@ -0,0 +1,33 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type uiConfigResponse struct {
+	// Keys maps actions such as "next-session" to KeyboardEvent.key values.
+	Keys map[string]string `json:"keys"`
+}
+
+// SetKeyBindings sets the keyboard shortcuts served by /api/ui-config.
+func (s *Server) SetKeyBindings(bindings map[string]string) {
+	keys := make(map[string]string, len(bindings))
+	for action, key := range bindings {
+		keys[action] = key
+	}
+	s.keyBindings = keys
+}
+
+func (s *Server) handleUIConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.NotFound(w, r)
+		return
+	}
+	response := uiConfigResponse{Keys: s.keyBindings}
+	if response.Keys == nil {
+		response.Keys = map[string]string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
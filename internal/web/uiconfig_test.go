@@ -0,0 +1,50 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleUIConfig(t *testing.T) {
+	server := newTestServer(t, t.TempDir())
+	server.SetKeyBindings(map[string]string{"next-session": "n", "search": "/"})
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ui-config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var response uiConfigResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(response.Keys) != 2 || response.Keys["next-session"] != "n" || response.Keys["search"] != "/" {
+		t.Fatalf("unexpected keys: %v", response.Keys)
+	}
+}
+
+func TestSessionPageAdjacentSessions(t *testing.T) {
+	sessionsDir := t.TempDir()
+	now := time.Now()
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "old.jsonl", "/proj", now.Add(-2*time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "mid.jsonl", "/proj", now.Add(-time.Hour))
+	writeSessionWithCwd(t, sessionsDir, "2026/01/09", "new.jsonl", "/proj", now)
+	server := newTestServer(t, sessionsDir)
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/mid.jsonl", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `id="prev-session" href="/2026/01/09/new.jsonl"`) || !strings.Contains(body, `id="next-session" href="/2026/01/09/old.jsonl"`) {
+		t.Fatalf("expected links to neighbouring day rows, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/2026/01/09/new.jsonl", nil))
+	if strings.Contains(rec.Body.String(), `id="prev-session"`) {
+		t.Fatalf("expected no previous link on the first row")
+	}
+}
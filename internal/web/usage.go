@@ -0,0 +1,170 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"codex-manager/internal/sessions"
+)
+
+// usageCache keeps aggregated reports until the sessions index changes;
+// parsing every file for token counts is too slow to repeat per request.
+type usageCache struct {
+	mu      sync.Mutex
+	updated time.Time
+	reports map[string]sessions.UsageReport
+}
+
+func newUsageCache() *usageCache {
+	return &usageCache{reports: map[string]sessions.UsageReport{}}
+}
+
+func (c *usageCache) get(updated time.Time, key string) (sessions.UsageReport, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.updated.Equal(updated) {
+		c.updated = updated
+		c.reports = map[string]sessions.UsageReport{}
+		return sessions.UsageReport{}, false
+	}
+	report, ok := c.reports[key]
+	return report, ok
+}
+
+func (c *usageCache) put(updated time.Time, key string, report sessions.UsageReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.updated.Equal(updated) {
+		c.reports[key] = report
+	}
+}
+
+type usagePageView struct {
+	From          string
+	To            string
+	Sessions      int
+	Rows          []usageRowView
+	Total         usageRowView
+	EstimatedCost string
+	HasPrices     bool
+	ThemeClass    string
+	ThemeVars     template.CSS
+}
+
+type usageRowView struct {
+	Model     string
+	Sessions  int
+	Input     string
+	Cached    string
+	Output    string
+	Reasoning string
+	Total     string
+	Cost      string
+}
+
+func newUsageRow(model string, sessionCount int, usage sessions.TokenUsage, cost string) usageRowView {
+	return usageRowView{
+		Model:     model,
+		Sessions:  sessionCount,
+		Input:     formatTokens(usage.InputTokens),
+		Cached:    formatTokens(usage.CachedInputTokens),
+		Output:    formatTokens(usage.OutputTokens),
+		Reasoning: formatTokens(usage.ReasoningOutputTokens),
+		Total:     formatTokens(usage.TotalTokens),
+		Cost:      cost,
+	}
+}
+
+// SetPriceTable sets the per-model prices /usage uses to estimate cost.
+func (s *Server) SetPriceTable(prices sessions.PriceTable) {
+	s.prices = prices
+	s.usage = newUsageCache()
+}
+
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	from, ok := usageDateParam(r, "from")
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "from must be yyyy-mm-dd")
+		return
+	}
+	to, ok := usageDateParam(r, "to")
+	if !ok {
+		writeJSONError(w, http.StatusBadRequest, "to must be yyyy-mm-dd")
+		return
+	}
+
+	updated := s.idx.LastUpdated()
+	key := from.String() + ".." + to.String()
+	report, ok := s.usage.get(updated, key)
+	if !ok {
+		var err error
+		report, err = sessions.AggregateUsage(s.idx, from, to, s.prices)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("usage aggregation failed: %v", err))
+			return
+		}
+		s.usage.put(updated, key, report)
+	}
+
+	if wantsHTML(r) {
+		view := usagePageView{
+			From:          report.From,
+			To:            report.To,
+			Sessions:      report.Sessions,
+			Total:         newUsageRow("Total", report.Sessions, report.Total, formatCost(report.EstimatedCost)),
+			EstimatedCost: formatCost(report.EstimatedCost),
+			HasPrices:     len(s.prices) > 0,
+			ThemeClass:    s.themeClass,
+			ThemeVars:     s.themeVars,
+		}
+		for _, model := range report.Models {
+			cost := "n/a"
+			if model.Priced {
+				cost = formatCost(model.Cost)
+			}
+			view.Rows = append(view.Rows, newUsageRow(model.Model, model.Sessions, model.Usage, cost))
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = s.renderer.Execute(w, "usage", view)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// usageDateParam parses an optional yyyy-mm-dd query value; empty means unbounded.
+func usageDateParam(r *http.Request, name string) (sessions.DateKey, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return sessions.DateKey{}, true
+	}
+	return sessions.ParseDateLabel(value)
+}
+
+func formatTokens(count int64) string {
+	s := strconv.FormatInt(count, 10)
+	if len(s) <= 3 {
+		return s
+	}
+	var out []byte
+	for i, ch := range []byte(s) {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, ch)
+	}
+	return string(out)
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("$%.2f", cost)
+}